@@ -0,0 +1,101 @@
+package toml
+
+import (
+	"strings"
+	"testing"
+)
+
+type aliasConfig struct {
+	MaxConns int    `toml:"max_conns,alias=connection_max,alias=maxconns,deprecated"`
+	Host     string `toml:"host,alias=server"`
+	Legacy   bool   `toml:"legacy,deprecated"`
+}
+
+func TestAliases(t *testing.T) {
+	var v aliasConfig
+	md, err := Decode("connection_max = 5\nserver = \"db\"\nlegacy = true", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.MaxConns != 5 || v.Host != "db" {
+		t.Errorf("Expected aliases to decode, but got %+v", v)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		t.Errorf("Expected aliases to be decoded, but %v weren't", undecoded)
+	}
+
+	want := []string{
+		"Near line 1, key 'connection_max': " +
+			"Key 'connection_max' is deprecated. Use 'max_conns' instead.",
+		"Near line 3, key 'legacy': Key 'legacy' is deprecated.",
+	}
+	warnings := md.Warnings()
+	if len(warnings) != len(want) {
+		t.Fatalf("Expected %d warnings but got %v", len(want), warnings)
+	}
+	for i, w := range warnings {
+		if w.String() != want[i] {
+			t.Errorf("Expected %q but got %q", want[i], w.String())
+		}
+	}
+
+	// Keys in their current names aren't deprecated.
+	md, err = Decode("max_conns = 5\nhost = \"db\"", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(md.Warnings()) > 0 {
+		t.Errorf("Expected no warnings but got %v", md.Warnings())
+	}
+}
+
+func TestAliasConflicts(t *testing.T) {
+	var v aliasConfig
+	_, err := Decode("max_conns = 5\n\nconnection_max = 6", &v)
+	want := "Near line 3, key 'connection_max': Cannot decode " +
+		"'toml.aliasConfig.MaxConns': Keys 'max_conns' and 'connection_max' " +
+		"are names of the same value, so only one of them may be set."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+
+	err = CheckType(map[string]interface{}{
+		"host": "a", "server": "b",
+	}, aliasConfig{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "Key 'server': Keys") {
+		t.Errorf("Expected a conflict at 'server' but got %v", err)
+	}
+}
+
+func TestAliasesStrict(t *testing.T) {
+	var v aliasConfig
+	md, err := DecodeStrict("maxconns = 5\nserver = \"db\"", &v, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.MaxConns != 5 || len(md.Warnings()) != 1 {
+		t.Errorf("Expected the alias to decode with a warning, "+
+			"but got %+v and %v", v, md.Warnings())
+	}
+}
+
+func TestAliasesPrimitiveDecode(t *testing.T) {
+	var v struct {
+		DB Primitive `toml:"db"`
+	}
+	md, err := Decode("[db]\nconnection_max = 5", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var db aliasConfig
+	if err := md.PrimitiveDecode(v.DB, &db); err != nil {
+		t.Fatal(err)
+	}
+	warnings := md.Warnings()
+	if db.MaxConns != 5 || len(warnings) != 1 ||
+		warnings[0].Key.String() != "db.connection_max" {
+
+		t.Errorf("Expected a warning for 'db.connection_max' but got %v",
+			warnings)
+	}
+}
@@ -31,6 +31,13 @@ func TestAllSpecs(t *testing.T) {
     r.AddSpec(PrimitiveDecodeStrictSpec)
     r.AddSpec(DecodeStrictInterfaceSpec)
     r.AddSpec(CheckTypeSpec)
+    r.AddSpec(CaseFoldingDecodeSpec)
+    r.AddSpec(KeyMapperDecodeSpec)
+    r.AddSpec(JSONTagsDecodeSpec)
+    r.AddSpec(UndecodedSpec)
+    r.AddSpec(StrictViolationsSpec)
+    r.AddSpec(PrimitiveContextSpec)
+    r.AddSpec(NumericCoercionSpec)
 
 	gospec.MainGoTest(r, t)
 }
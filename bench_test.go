@@ -0,0 +1,72 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+type hardConfig struct {
+	The struct {
+		TestString string `toml:"test_string"`
+		Hard       struct {
+			TestArray         []string `toml:"test_array"`
+			TestArray2        []string `toml:"test_array2"`
+			AnotherTestString string   `toml:"another_test_string"`
+			HarderTestString  string   `toml:"harder_test_string"`
+			Bit               struct {
+				What           string   `toml:"what?"`
+				MultiLineArray []string `toml:"multi_line_array"`
+			} `toml:"bit#"`
+		}
+	}
+}
+
+func BenchmarkDecodeHard(b *testing.B) {
+	data, err := ioutil.ReadFile("_examples/hard.toml")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var v hardConfig
+		dec := NewDecoder(bytes.NewReader(data))
+		if _, err := dec.DecodeStrict(&v, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecode10kKeys decodes 100 tables of 100 keys each into structs
+// with 100 fields, whose keys only match their field names case
+// insensitively.
+func BenchmarkDecode10kKeys(b *testing.B) {
+	var buf bytes.Buffer
+	fields := make([]reflect.StructField, 100)
+	for i := range fields {
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: reflect.TypeOf(0),
+		}
+	}
+	for t := 0; t < 100; t++ {
+		fmt.Fprintf(&buf, "[t%d]\n", t)
+		for i := range fields {
+			fmt.Fprintf(&buf, "f%d = %d\n", i, i)
+		}
+	}
+	data := buf.Bytes()
+	rt := reflect.MapOf(reflect.TypeOf(""), reflect.StructOf(fields))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := reflect.New(rt).Interface()
+		dec := NewDecoder(bytes.NewReader(data))
+		if _, err := dec.DecodeStrict(v, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package toml
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// isBigType returns true if values of type `rt` are decoded from numbers by
+// the math/big methods rather than by reflection.
+func isBigType(rt reflect.Type) bool {
+	return rt == bigIntType || rt == bigFloatType || rt == bigRatType
+}
+
+// parseBigInt parses an integer literal that is out of the range of int64.
+func parseBigInt(lit string) *big.Int {
+	num, _ := new(big.Int).SetString(lit, 10)
+	return num
+}
+
+// parseBigFloat parses the float literal `lit` into a *big.Float with
+// enough precision to keep all of its digits, if it doesn't fit a float64
+// exactly. The float64 `num` is the literal parsed as a float64, and `err`
+// the error from parsing it.
+func parseBigFloat(lit string, num float64, err error) (*big.Float, bool) {
+	if err == nil {
+		// The literal fits if it is the shortest representation of a
+		// float64, ignoring trailing zeros and such. Comparing the text
+		// settles that for almost every literal without allocating.
+		if sameDecimal(lit, num) {
+			return nil, false
+		}
+		want, _ := new(big.Rat).SetString(lit)
+		got, _ := new(big.Rat).SetString(
+			strconv.FormatFloat(num, 'f', -1, 64))
+		if want.Cmp(got) == 0 {
+			return nil, false
+		}
+	}
+
+	// Each decimal digit takes less than 4 bits.
+	prec := uint(len(lit)) * 4
+	if prec < 64 {
+		prec = 64
+	}
+	f, _, perr := big.ParseFloat(lit, 10, prec, big.ToNearestEven)
+	return f, perr == nil
+}
+
+// sameDecimal returns true if the float literal `lit` is the shortest
+// decimal representation of `num`, once leading and trailing zeros are
+// trimmed.
+func sameDecimal(lit string, num float64) bool {
+	var buf [32]byte
+	s := strconv.AppendFloat(buf[:0], num, 'f', -1, 64)
+	neg := len(s) > 0 && s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+
+	switch {
+	case strings.HasPrefix(lit, "-"):
+		if !neg {
+			return false
+		}
+		lit = lit[1:]
+	case neg:
+		return false
+	case strings.HasPrefix(lit, "+"):
+		lit = lit[1:]
+	}
+	if strings.Contains(lit, ".") {
+		lit = strings.TrimRight(strings.TrimRight(lit, "0"), ".")
+	}
+	for len(lit) > 1 && lit[0] == '0' && lit[1] != '.' {
+		lit = lit[1:]
+	}
+	return lit == string(s)
+}
+
+// unifyBig decodes a number into the math/big value `rv`, which is a
+// big.Int, big.Float or big.Rat.
+func unifyBig(data interface{}, rv reflect.Value) error {
+	switch z := rv.Addr().Interface().(type) {
+	case *big.Int:
+		switch num := data.(type) {
+		case int64:
+			z.SetInt64(num)
+			return nil
+		case *big.Int:
+			z.Set(num)
+			return nil
+		}
+		return badtype("integer", data)
+	case *big.Float:
+		switch num := data.(type) {
+		case int64:
+			z.SetInt64(num)
+			return nil
+		case *big.Int:
+			z.SetInt(num)
+			return nil
+		case float64:
+			z.SetFloat64(num)
+			return nil
+		case *big.Float:
+			z.Set(num)
+			return nil
+		}
+		return badtype("number", data)
+	}
+
+	// Rationals are exact, so floats are taken as the decimals they were
+	// written as.
+	z := rv.Addr().Interface().(*big.Rat)
+	switch num := data.(type) {
+	case int64:
+		z.SetInt64(num)
+		return nil
+	case *big.Int:
+		z.SetInt(num)
+		return nil
+	case float64:
+		z.SetString(strconv.FormatFloat(num, 'f', -1, 64))
+		return nil
+	case *big.Float:
+		z.SetString(num.Text('g', -1))
+		return nil
+	}
+	return badtype("number", data)
+}
+
+// bigAsInt returns an error unless the integer `num` fits in a value of the
+// integer type `rt`.
+func bigAsInt(num *big.Int, rt reflect.Type) error {
+	zero := reflect.Zero(rt)
+	if rt.Kind() >= reflect.Uint {
+		if num.Sign() >= 0 && num.IsUint64() &&
+			!zero.OverflowUint(num.Uint64()) {
+
+			return nil
+		}
+	} else if num.IsInt64() && !zero.OverflowInt(num.Int64()) {
+		return nil
+	}
+	return e("Integer '%s' is out of the range of %s.", num, rt)
+}
+
+// bigAsFloat returns the float `num` as a float64, or an error if it is out
+// of the range of the float type `rt`.
+func bigAsFloat(num *big.Float, rt reflect.Type) (float64, error) {
+	f, _ := num.Float64()
+	if math.IsInf(f, 0) || reflect.Zero(rt).OverflowFloat(f) {
+		return 0, e("Float '%s' is out of the range of %s.",
+			num.Text('g', -1), rt)
+	}
+	return f, nil
+}
@@ -0,0 +1,236 @@
+package toml
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var bigData = `
+id = 170141183460469231731687303715884105727
+small = 42
+rate = 0.12345678901234567890123
+ratio = 0.5
+neg = -9223372036854775809
+`
+
+// decodeBig decodes `data` into `v` with the BigNumbers option.
+func decodeBig(data string, v interface{}) (MetaData, error) {
+	dec := NewDecoder(strings.NewReader(data))
+	dec.BigNumbers = true
+	return dec.Decode(v)
+}
+
+func TestBigNumbers(t *testing.T) {
+	var v map[string]interface{}
+	if _, err := Decode(bigData, &v); err == nil {
+		t.Fatalf("Expected an out of range error without BigNumbers")
+	}
+
+	if _, err := decodeBig(bigData, &v); err != nil {
+		t.Fatal(err)
+	}
+	id, ok := v["id"].(*big.Int)
+	if !ok || id.String() != "170141183460469231731687303715884105727" {
+		t.Errorf("Expected a *big.Int but got %#v", v["id"])
+	}
+	if _, ok := v["small"].(int64); !ok {
+		t.Errorf("Expected integers that fit to stay int64, but got %T",
+			v["small"])
+	}
+	rate, ok := v["rate"].(*big.Float)
+	if !ok || rate.Text('g', -1) != "0.12345678901234567890123" {
+		t.Errorf("Expected a precise *big.Float but got %#v", v["rate"])
+	}
+	if _, ok := v["ratio"].(float64); !ok {
+		t.Errorf("Expected floats that fit to stay float64, but got %T",
+			v["ratio"])
+	}
+}
+
+func TestBigNumberTargets(t *testing.T) {
+	var v struct {
+		ID    big.Int
+		Small *big.Int
+		Rate  *big.Rat
+		Ratio big.Rat
+		Float big.Float `toml:"rate"`
+		Neg   *big.Float
+	}
+	if _, err := decodeBig(bigData, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID.String() != "170141183460469231731687303715884105727" ||
+		v.Small.Int64() != 42 {
+
+		t.Errorf("Unexpected integers %s and %s", &v.ID, v.Small)
+	}
+	want, _ := new(big.Rat).SetString("0.12345678901234567890123")
+	if v.Rate.Cmp(want) != 0 || v.Ratio.String() != "1/2" {
+		t.Errorf("Expected exact rationals, but got %s and %s", v.Rate,
+			&v.Ratio)
+	}
+	if v.Float.Text('g', -1) != "0.12345678901234567890123" {
+		t.Errorf("Unexpected float %s", v.Float.Text('g', -1))
+	}
+	if v.Neg.String() != "-9.223372037e+18" {
+		t.Errorf("Unexpected float %s", v.Neg)
+	}
+
+	// Without BigNumbers, big values may still be decoded from numbers that
+	// fit.
+	var small struct{ Ratio big.Rat }
+	if _, err := Decode("ratio = 0.1", &small); err != nil {
+		t.Fatal(err)
+	}
+	if small.Ratio.String() != "1/10" {
+		t.Errorf("Expected 1/10 but got %s", &small.Ratio)
+	}
+}
+
+func TestBigNumberErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		v    interface{}
+		want string
+	}{
+		{
+			"id = 170141183460469231731687303715884105727",
+			&struct{ ID int64 }{},
+			"Near line 1, key 'id': Type mismatch for " +
+				"'struct { ID int64 }.ID': Integer " +
+				"'170141183460469231731687303715884105727' is out of the " +
+				"range of int64.",
+		},
+		{
+			"id = 18446744073709551615",
+			&struct{ ID uint8 }{},
+			"Near line 1, key 'id': Type mismatch for " +
+				"'struct { ID uint8 }.ID': Integer '18446744073709551615' " +
+				"is out of the range of uint8.",
+		},
+		{
+			"id = 1.5",
+			&struct{ ID big.Int }{},
+			"Near line 1, key 'id': Type mismatch for " +
+				"'struct { ID big.Int }.ID': Expected integer but found " +
+				"'float64'.",
+		},
+	}
+	for _, test := range tests {
+		_, err := decodeBig(test.data, test.v)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Expected %q but got %v", test.want, err)
+		}
+	}
+
+	var v struct{ ID uint64 }
+	_, err := decodeBig("id = 18446744073709551615", &v)
+	if err != nil || v.ID != 1<<64-1 {
+		t.Errorf("Expected the largest uint64 but got %d (%v)", v.ID, err)
+	}
+}
+
+func TestBigIntRanges(t *testing.T) {
+	tests := []struct {
+		num  int64
+		v    interface{}
+		want interface{}
+	}{
+		{0, new(int), 0},
+		{-5, new(int), -5},
+		{5, new(int), 5},
+		{127, new(int8), int8(127)},
+		{-128, new(int8), int8(-128)},
+		{128, new(int8), nil},
+		{-129, new(int8), nil},
+		{0, new(uint), uint(0)},
+		{7, new(uint), uint(7)},
+		{-1, new(uint), nil},
+	}
+	for _, test := range tests {
+		tree, err := LoadTree("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tree.Set("n", big.NewInt(test.num)); err != nil {
+			t.Fatal(err)
+		}
+		rt := reflect.TypeOf(test.v).Elem()
+		into := reflect.New(reflect.StructOf([]reflect.StructField{{
+			Name: "N",
+			Type: rt,
+		}}))
+		_, err = tree.Decode(into.Interface())
+		got := into.Elem().Field(0).Interface()
+		switch {
+		case test.want == nil && err == nil:
+			t.Errorf("%d into %s: Expected a range error but got %v",
+				test.num, rt, got)
+		case test.want != nil && (err != nil || got != test.want):
+			t.Errorf("%d into %s: Expected %v but got %v (%v)",
+				test.num, rt, test.want, got, err)
+		}
+	}
+}
+
+func TestBigNumbersStrict(t *testing.T) {
+	var v struct {
+		ID   big.Int
+		Rate float64
+		Lit  Literal
+	}
+	dec := NewDecoder(strings.NewReader(
+		"id = 99999999999999999999\nrate = 0.5\nlit = [1]"))
+	dec.BigNumbers = true
+	if _, err := dec.DecodeStrict(&v, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var bad struct{ ID int64 }
+	dec = NewDecoder(strings.NewReader("id = 99999999999999999999"))
+	dec.BigNumbers = true
+	_, err := dec.DecodeStrict(&bad, nil)
+	if err == nil {
+		t.Fatalf("Expected an out of range error")
+	}
+}
+
+func TestSameDecimal(t *testing.T) {
+	tests := []struct {
+		lit  string
+		same bool
+	}{
+		{"1.5", true},
+		{"1.50", true},
+		{"0.1", true},
+		{"0.0", true},
+		{"-0.0", true},
+		{"-2.25", true},
+		{"+2.25", true},
+		{"007.25", true},
+		{"100.0", true},
+		{"0.12345678901234567890123", false},
+		{"1.0000000000000000001", false},
+		{"9007199254740993.0", false},
+	}
+	for _, test := range tests {
+		num, err := strconv.ParseFloat(test.lit, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sameDecimal(test.lit, num); got != test.same {
+			t.Errorf("%s: Expected %v but got %v", test.lit, test.same, got)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		parseBigFloat("0.25", 0.25, nil)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected floats that fit not to allocate, but got %v "+
+			"allocations", allocs)
+	}
+}
@@ -112,7 +112,7 @@ ip = "10.0.0.1"
 	c.Assume(err, gs.IsNil)
 
 	_, err = DecodeStrict(testBadArg, &val, empty_ignore)
-	c.Assume(err.Error(), gs.Equals, "Configuration contains key [not_andrew] which doesn't exist in struct")
+	c.Assume(err.Error(), gs.Equals, "Near line 4, key 'not_andrew': Configuration contains key [not_andrew] which doesn't exist in struct")
 
 }
 
@@ -161,3 +161,98 @@ encoding_name = "PROTOCOL_BUFFER"
 	c.Expect(myproto["encoding_name"], gs.Equals, "PROTOCOL_BUFFER")
 
 }
+
+func StrictViolationsSpec(c gs.Context) {
+	type band struct {
+		Started int
+	}
+
+	type database struct {
+		IPAddress string `toml:"ip"`
+	}
+
+	type config struct {
+		Title    string
+		Servers  map[string]string
+		Bands    map[string]band
+		Database database
+	}
+
+	c.Specify("mismatched data never panics", func() {
+		err := CheckType("title = 5", config{}, nil)
+		c.Assume(err, gs.Not(gs.IsNil))
+		c.Expect(err.Error(), gs.Equals,
+			"Expected data to be a map: [title = 5]")
+
+		m := map[string]interface{}{"title": "ok", "bands": []interface{}{1}}
+		err = CheckType(m, config{}, nil)
+		c.Assume(err, gs.Not(gs.IsNil))
+		c.Expect(err.Error(), gs.Equals,
+			"Key 'bands': Expected data to be a map: [[1]]")
+	})
+
+	c.Specify("every violation is reported", func() {
+		m := map[string]interface{}{
+			"title":   []interface{}{"not", "a", "string"},
+			"servers": "not a table",
+			"bands": map[string]interface{}{
+				"Springsteen": map[string]interface{}{
+					"type":    "ignore_this",
+					"started": "not an int",
+				},
+				"J Geils": map[string]interface{}{
+					"type":    "ignore_this",
+					"started": int64(1970),
+				},
+			},
+			"database": map[string]interface{}{
+				"ip":   int64(5),
+				"type": "not ignored",
+			},
+		}
+		ignore := map[string]interface{}{"bands.*.type": true}
+		c.Expect(CheckType(m, config{}, ignore).Error(), gs.Equals,
+			"Key 'title': Incoming type didn't match gotype string\n"+
+				"Key 'servers': Expected data to be a map: [not a table]\n"+
+				"Key 'bands.Springsteen.started': "+
+				"Incoming type didn't match gotype int\n"+
+				"Key 'database.type': "+
+				"Configuration contains key [type] which doesn't exist in struct\n"+
+				"Key 'database.ip': Incoming type didn't match gotype string")
+
+		se, ok := CheckType(m, config{}, ignore).(*StrictError)
+		c.Assume(ok, gs.IsTrue)
+		c.Expect(len(se.Violations), gs.Equals, 5)
+	})
+
+	c.Specify("strict decoding reports every violation with positions",
+		func() {
+
+			var tomlBlob = `
+[bands.Springsteen]
+type = "ignore_this"
+started = "1973"
+
+[bands.J Geils]
+typo = "oops"
+started = 1970
+`
+			var val struct {
+				Bands map[string]band
+			}
+			ignore := map[string]interface{}{"bands.*.type": true}
+			_, err := DecodeStrict(tomlBlob, &val, ignore)
+			c.Assume(err, gs.Not(gs.IsNil))
+			se, ok := err.(*StrictError)
+			c.Assume(ok, gs.IsTrue)
+			c.Assume(len(se.Violations), gs.Equals, 2)
+			c.Expect(se.Violations[0].Key.String(), gs.Equals,
+				"bands.Springsteen.started")
+			c.Expect(se.Violations[0].Message, gs.Equals,
+				"Incoming type didn't match gotype int")
+			c.Expect(se.Violations[0].Position.Line, gs.Equals, 4)
+			c.Expect(se.Violations[1].Key.String(), gs.Equals,
+				"bands.J Geils.typo")
+			c.Expect(se.Violations[1].Position.Line, gs.Equals, 7)
+		})
+}
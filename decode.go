@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/big"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,15 +18,55 @@ var e = fmt.Errorf
 // When using the various `Decode*` functions, the type `Primitive` may
 // be given to any value, and its decoding will be delayed.
 //
-// A `Primitive` value can be decoded using the `PrimitiveDecode` function.
+// A `Primitive` value can be decoded using the `PrimitiveDecode` function,
+// or the `PrimitiveDecode` method of the MetaData it came with.
 //
-// The underlying representation of a `Primitive` value is subject to change.
-// Do not rely on it.
+// A `Primitive` value remembers the key it was found at and where that key
+// was defined, so that errors in deferred decoding can say where they
+// happened. Its representation is otherwise opaque.
 //
 // N.B. Primitive values are still parsed, so using them will only avoid
 // the overhead of reflection. They can be useful when you don't know the
 // exact type of TOML data until run time.
-type Primitive interface{}
+type Primitive struct {
+	undecoded interface{}
+
+	// the full key of the value, used to track which keys get decoded
+	context Key
+
+	// where the key of the value was defined
+	position Position
+
+	// the meta data shared with the MetaData this value came from
+	decoded   map[string]bool
+	positions map[string]Position
+	warnings  *[]Warning
+}
+
+// Key returns the full key of the primitive value.
+func (p Primitive) Key() Key {
+	return p.context
+}
+
+// Position returns where the key of the primitive value was defined.
+func (p Primitive) Position() Position {
+	return p.position
+}
+
+var primitiveType = reflect.TypeOf(Primitive{})
+
+// Unmarshaler is implemented by types that decode themselves from TOML data,
+// such as those that the tomlgen command generates code for. The data is in
+// the form that decoding into an `interface{}` produces: tables are of type
+// `map[string]interface{}` and arrays of type `[]interface{}`.
+//
+// If UnmarshalTOML returns a *DecodeError, its key is taken to be relative
+// to the key of the value being decoded. All keys inside the value are
+// considered decoded when UnmarshalTOML succeeds, unless the type implements
+// GeneratedUnmarshaler.
+type Unmarshaler interface {
+	UnmarshalTOML(data interface{}) error
+}
 
 // PrimitiveDecode is just like the other `Decode*` functions, except it
 // decodes a TOML value that has already been parsed. Valid primitive values
@@ -32,9 +75,46 @@ type Primitive interface{}
 // values.)
 //
 // Meta data for primitive values is included in the meta data returned by
-// the `Decode*` functions.
+// the `Decode*` functions. Keys decoded here are no longer reported by
+// (MetaData).Undecoded.
+//
+// PrimitiveDecode always uses the default decoding options. Use the
+// `PrimitiveDecode` method of MetaData to apply the options of the Decoder
+// the primitive value came from.
 func PrimitiveDecode(primValue Primitive, v interface{}) error {
-	return unify(primValue, rvalue(v))
+	md := MetaData{
+		decoded:   primValue.decoded,
+		positions: primValue.positions,
+		warnings:  primValue.warnings,
+		dec:       new(Decoder),
+	}
+	return md.PrimitiveDecode(primValue, v)
+}
+
+// PrimitiveDecode is just like the package level `PrimitiveDecode`, except
+// the options of the Decoder that produced the meta data apply.
+func (md MetaData) PrimitiveDecode(primValue Primitive, v interface{}) error {
+	if md.decoded == nil {
+		md.decoded = make(map[string]bool)
+	}
+	if md.dec == nil {
+		md.dec = new(Decoder)
+	}
+	if md.secrets == nil {
+		md.secrets = make(map[string]bool)
+	}
+	if md.warnings == nil {
+		md.warnings = new([]Warning)
+	}
+	md.context = primValue.context
+	found := len(*md.warnings)
+	if err := md.unify(primValue.undecoded, rvalue(v)); err != nil {
+		return err
+	}
+	if err := md.validate(primValue.undecoded, rvalue(v)); err != nil {
+		return err
+	}
+	return md.promoted(found)
 }
 
 // Decode will decode the contents of `data` in TOML format into a pointer
@@ -48,11 +128,43 @@ func PrimitiveDecode(primValue Primitive, v interface{}) error {
 // All other TOML types (float, string, int, bool and array) correspond
 // to the obvious Go types.
 //
+// Pointers are allocated as needed, but only for keys that exist in the
+// TOML data: a pointer field whose key is absent is left nil. The `Optional`
+// type offers the same distinction between absent keys and zero values
+// without pointers.
+//
 // TOML keys can map to either keys in a Go map or field names in a Go
 // struct. The special `toml` struct tag may be used to map TOML keys to
 // struct fields that don't match the key name exactly. (See the example.)
 // A case insensitive match to struct names will be tried if an exact match
-// can't be found.
+// can't be found. If several keys match a field case insensitively, an error
+// is returned. (Use a `Decoder` with `NoCaseFolding` to require exact matches.)
+//
+// Struct fields may have a `validate` tag with rules that the decoded values
+// must follow, separated by commas:
+//
+//	min=N, max=N   numbers, or the lengths of strings, slices and maps,
+//	               must be at least or at most N
+//	oneof=a b c    the value must be one of the values separated by spaces
+//	regexp=RE      strings must match the regular expression RE, which runs
+//	               to the end of the tag
+//	nonempty       the value must not be zero, empty or absent
+//
+// e.g., `validate:"min=1,max=65535"`. Rules are checked once the data is
+// decoded, against every field, whether or not its key was in the data. Nil
+// pointers and absent Optional values only break `nonempty` rules. If any
+// value breaks a rule, the error returned is a *StrictError that lists every
+// such value with its key.
+//
+// The `alias` option gives a field other key names, such as the names it used
+// to have, as in `toml:"max_conns,alias=connection_max"`; it may be given
+// more than once. Setting a field under more than one of its names is an
+// error. With the `deprecated` option, using an alias, or the key name of a
+// field without aliases, is reported by (MetaData).Warnings.
+//
+// The values of fields with the `secret` option, as in `toml:"token,secret"`,
+// and of fields of type Secret are decoded as usual, but kept out of error
+// messages and of (MetaData).Dump.
 //
 // The mapping between TOML values and Go values is loose. That is, there
 // may exist TOML values that cannot be placed into your representation, and
@@ -62,31 +174,159 @@ func PrimitiveDecode(primValue Primitive, v interface{}) error {
 // This decoder will not handle cyclic types. If a cyclic type is passed,
 // `Decode` will not terminate.
 func Decode(data string, v interface{}) (MetaData, error) {
-	p, err := parse(data)
-	if err != nil {
-		return MetaData{}, err
-	}
-	return MetaData{p.mapping, p.types, p.ordered}, unify(p.mapping, rvalue(v))
+	return new(Decoder).decode(data, "", v)
 }
 
 // DecodeFile is just like Decode, except it will automatically read the
 // contents of the file at `fpath` and decode it for you.
 func DecodeFile(fpath string, v interface{}) (MetaData, error) {
+	return new(Decoder).DecodeFile(fpath, v)
+}
+
+// DecodeReader is just like Decode, except it will consume all bytes
+// from the reader and decode it for you.
+func DecodeReader(r io.Reader, v interface{}) (MetaData, error) {
+	return NewDecoder(r).Decode(v)
+}
+
+// Decoder decodes TOML data read from an input stream. Its exported fields
+// control how TOML keys and values are mapped onto Go values. They may be
+// changed freely before calling Decode; the defaults match the behavior of
+// the package level `Decode*` functions.
+type Decoder struct {
+	// NoCaseFolding disables the case insensitive fallback used when a
+	// struct field has no exact match in the TOML data. With it set, TOML
+	// keys must match field names (or `toml` tags) exactly.
+	NoCaseFolding bool
+
+	// KeyMapper, when set, maps the names of struct fields without a `toml`
+	// tag to TOML keys. e.g., `SnakeCase` lets `ConnectionMax` match the
+	// key `connection_max`.
+	KeyMapper KeyMapper
+
+	// UseJSONTags makes fields without a `toml` tag use the name and options
+	// of their `json` tag instead, if they have one.
+	UseJSONTags bool
+
+	// CoerceNumbers allows TOML integers to be decoded into Go floats, and
+	// TOML floats into Go integers, as long as no precision is lost. e.g.,
+	// `ratio = 1` fits a float64 and `workers = 3.0` fits an int, but
+	// `workers = 3.5` is an error.
+	CoerceNumbers bool
+
+	// ExpandEnv enables the expansion of `${VAR}` and `${VAR:-default}`
+	// references to environment variables in basic strings. `$$` stands for
+	// a literal `$`. Literal strings ('...') are never expanded. Referring to
+	// an undefined variable without a default is an error.
+	ExpandEnv bool
+
+	// LookupEnv, when set, is used instead of os.LookupEnv to find the values
+	// of environment variables.
+	LookupEnv func(name string) (string, bool)
+
+	// IncludeKey, when set, names a top-level key that acts as an include
+	// directive rather than a value, e.g., "include". Its value is a file
+	// name or an array of file names, which may be glob patterns, relative
+	// to the directory of the including file. Included files are parsed as
+	// if their contents appeared in place of the directive: defining a key
+	// in more than one file is an error, just like defining it twice in one
+	// file. Includes may nest, but not in cycles.
+	IncludeKey string
+
+	// IgnoreUnmarshalers makes the decoder use reflection for every value,
+	// even if its type implements Unmarshaler. It is mostly useful to check
+	// code generated by tomlgen against the decoder.
+	IgnoreUnmarshalers bool
+
+	// BigNumbers makes integers that are out of the range of int64 decode
+	// as *big.Int values, instead of being an error, and floats that don't
+	// fit a float64 exactly decode as *big.Float values with enough
+	// precision to keep all of their digits. Such numbers can be decoded
+	// into big.Int, big.Float and big.Rat values, or into empty interfaces.
+	// Numbers that fit are decoded as usual, so values of types like int64
+	// and float64 are unaffected.
+	//
+	// big.Int, big.Float and big.Rat values may be decoded into whether or
+	// not BigNumbers is set. Floats decoded into a big.Rat are taken as the
+	// decimals they were written as, so `rate = 0.1` is exactly 1/10.
+	BigNumbers bool
+
+	// Hooks decode values of types the decoder doesn't know how to decode by
+	// itself. See DecodeHook.
+	Hooks []DecodeHook
+
+	// PromoteWarnings lists kinds of warnings that are errors instead. If
+	// decoding finds any, the error returned is a *StrictError that lists
+	// all of them, once the data has been decoded. They are still listed by
+	// (MetaData).Warnings.
+	PromoteWarnings []WarningKind
+
+	r io.Reader
+}
+
+// NewDecoder returns a new decoder that reads from `r`.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode consumes all bytes from the decoder's reader and decodes them into
+// the pointer `v`. See the package level `Decode` for details.
+func (dec *Decoder) Decode(v interface{}) (MetaData, error) {
+	if dec.r == nil {
+		return MetaData{}, e("Decoder has no reader to decode from.")
+	}
+	bs, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return MetaData{}, err
+	}
+	return dec.decode(string(bs), "", v)
+}
+
+// DecodeFile reads and decodes the file at `fpath`, ignoring the decoder's
+// reader. The file name is recorded in the positions of its keys, and is
+// the base of relative paths in include directives.
+func (dec *Decoder) DecodeFile(fpath string, v interface{}) (MetaData, error) {
 	bs, err := ioutil.ReadFile(fpath)
 	if err != nil {
 		return MetaData{}, err
 	}
-	return Decode(string(bs), v)
+	return dec.decode(string(bs), fpath, v)
 }
 
-// DecodeReader is just like Decode, except it will consume all bytes
-// from the reader and decode it for you.
-func DecodeReader(r io.Reader, v interface{}) (MetaData, error) {
-	bs, err := ioutil.ReadAll(r)
+// decode decodes TOML data read from the file `fpath`, which is empty if the
+// data didn't come from a file.
+func (dec *Decoder) decode(
+	data, fpath string, v interface{}) (MetaData, error) {
+
+	p, err := dec.parse(data, fpath)
 	if err != nil {
 		return MetaData{}, err
 	}
-	return Decode(string(bs), v)
+	return dec.unifyParsed(p, v)
+}
+
+// unifyParsed decodes the data of a parser into `v`.
+func (dec *Decoder) unifyParsed(p *parser, v interface{}) (MetaData, error) {
+	md := MetaData{
+		mapping:   p.mapping,
+		types:     p.types,
+		keys:      p.ordered,
+		decoded:   make(map[string]bool),
+		positions: p.positions,
+		literals:  p.literals,
+		secrets:   p.secrets,
+		warnings:  new([]Warning),
+		dec:       dec,
+	}
+	*md.warnings = append(*md.warnings, p.warnings...)
+	err := md.unify(p.mapping, rvalue(v))
+	if err == nil {
+		err = md.validate(p.mapping, rvalue(v))
+	}
+	if err == nil {
+		err = md.promoted(0)
+	}
+	return md, err
 }
 
 // unify performs a sort of type unification based on the structure of `rv`,
@@ -94,10 +334,56 @@ func DecodeReader(r io.Reader, v interface{}) (MetaData, error) {
 //
 // Any type mismatch produces an error. Finding a type that we don't know
 // how to handle produces an unsupported type error.
-func unify(data interface{}, rv reflect.Value) error {
+func (md *MetaData) unify(data interface{}, rv reflect.Value) error {
+	// Special case. An `Optional` value is decoded as the value it wraps,
+	// and remembers that it was set.
+	if rv.CanAddr() {
+		if opt, ok := rv.Addr().Interface().(optional); ok {
+			return md.unify(data, indirect(opt.setValue()))
+		}
+	}
+
+	// Special case. A `Secret` is decoded as a string, but its value is kept
+	// out of error messages and dumps.
+	if rv.Type() == secretType {
+		md.secrets[md.context.String()] = true
+	}
+
+	// Special case. Hooks of the decoder come before its own rules.
+	if len(md.dec.Hooks) > 0 {
+		var done bool
+		var err error
+		if data, done, err = md.unifyHooks(data, rv); done {
+			return err
+		}
+	}
+
+	// Special case. Types that decode themselves are left to do so.
+	// Generated code is used only if it decodes as reflection would.
+	if rv.CanAddr() && !md.dec.IgnoreUnmarshalers {
+		switch u := rv.Addr().Interface().(type) {
+		case GeneratedUnmarshaler:
+			if md.useGenerated(u) {
+				return md.unifyGenerated(data, u)
+			}
+		case Unmarshaler:
+			return md.unifyUnmarshaler(data, u)
+		}
+	}
+
 	// Special case. Look for a `Primitive` value.
-	if rv.Type() == reflect.TypeOf((*Primitive)(nil)).Elem() {
-		return unifyAnything(data, rv)
+	if rv.Type() == primitiveType {
+		return md.unifyPrimitive(data, rv)
+	}
+
+	// Special case. A `Literal` value gets how the value was written.
+	if rv.Type() == literalType {
+		return md.unifyLiteral(data, rv)
+	}
+
+	// Special case. Arbitrary-precision numbers from math/big.
+	if isBigType(rv.Type()) {
+		return unifyBig(data, rv)
 	}
 
 	// Special case. Go's `time.Time` is a struct, which we don't want
@@ -110,15 +396,15 @@ func unify(data interface{}, rv reflect.Value) error {
 
 	// laziness
 	if k >= reflect.Int && k <= reflect.Uint64 {
-		return unifyInt(data, rv)
+		return md.unifyInt(data, rv)
 	}
 	switch k {
 	case reflect.Struct:
-		return unifyStruct(data, rv)
+		return md.unifyStruct(data, rv)
 	case reflect.Map:
-		return unifyMap(data, rv)
+		return md.unifyMap(data, rv)
 	case reflect.Slice:
-		return unifySlice(data, rv)
+		return md.unifySlice(data, rv)
 	case reflect.String:
 		return unifyString(data, rv)
 	case reflect.Bool:
@@ -128,51 +414,90 @@ func unify(data interface{}, rv reflect.Value) error {
 		if rv.NumMethod() > 0 {
 			e("Unsupported type '%s'.", rv.Kind())
 		}
-		return unifyAnything(data, rv)
+		return md.unifyAnything(data, rv)
 	case reflect.Float32:
 		fallthrough
 	case reflect.Float64:
-		return unifyFloat64(data, rv)
+		return md.unifyFloat64(data, rv)
 	}
 	return e("Unsupported type '%s'.", rv.Kind())
 }
 
-func unifyStruct(mapping interface{}, rv reflect.Value) error {
+func (md *MetaData) unifyStruct(mapping interface{}, rv reflect.Value) error {
 	tmap, ok := mapping.(map[string]interface{})
 	if !ok {
 		return mismatch(rv, "map", mapping)
 	}
 
 	rt := rv.Type()
-	for i := 0; i < rt.NumField(); i++ {
+	used := make(map[string]bool, len(tmap))
+	ix := newKeyIndex(tmap, !md.dec.NoCaseFolding)
+	fields := structFields(rt, md.dec.KeyMapper, md.dec.UseJSONTags)
+	for i, f := range fields {
 		// A little tricky. We want to use the special `toml` name in the
 		// struct tag if it exists. In particular, we need to make sure that
 		// this struct field is in the current map before trying to unify it.
-		sft := rt.Field(i)
-		kname := sft.Tag.Get("toml")
-		if len(kname) == 0 {
-			kname = sft.Name
+		sft, opts := f.sft, f.opts
+		if opts.skip {
+			continue
 		}
-		if datum, ok := insensitiveGet(tmap, kname); ok {
-			sf := indirect(rv.Field(i))
+		key, ok, alias, err := matchField(ix, f)
+		if err != nil {
+			at := md.context
+			if len(key) > 0 {
+				at = at.add(key)
+			}
+			return md.errorAt(at, e("Cannot decode '%s.%s': %s",
+				rt.String(), sft.Name, err))
+		}
+		if ok {
+			sf := rv.Field(i)
+			if opts.deprecated && (alias || len(opts.aliases) == 0) {
+				md.deprecated(md.context.add(key), opts.name, alias)
+			}
+			if key != opts.name && !Contains(opts.aliases, key) {
+				md.warn(CaseFoldedKey, md.context.add(key), "Key '%s' "+
+					"matches the field '%s.%s' only case insensitively.",
+					key, rt.String(), sft.Name)
+			}
 
 			// Don't try to mess with unexported types and other such things.
+			// Pointers are only allocated here, once we know the key exists,
+			// so that pointer fields stay nil when their keys are absent.
 			if sf.CanSet() {
-				if err := unify(datum, sf); err != nil {
-					return e("Type mismatch for '%s.%s': %s",
-						rt.String(), sft.Name, err)
+				used[key] = true
+				md.decoded[md.context.add(key).String()] = true
+				if opts.secret {
+					md.secrets[md.context.add(key).String()] = true
+				}
+				md.context = append(md.context, key)
+				err := md.unify(tmap[key], indirect(sf))
+				md.context = md.context[0 : len(md.context)-1]
+				if _, ok := err.(*DecodeError); ok {
+					return err
+				}
+				if err != nil {
+					return md.errorAt(md.context.add(key),
+						e("Type mismatch for '%s.%s': %s",
+							rt.String(), sft.Name, err))
 				}
-			} else if len(sft.Tag.Get("toml")) > 0 {
+			} else if opts.tagged {
 				// Bad user! No soup for you!
 				return e("Field '%s.%s' is unexported, and therefore cannot "+
 					"be loaded with reflection.", rt.String(), sft.Name)
 			}
 		}
 	}
+	for _, k := range sortedKeys(tmap) {
+		if !used[k] {
+			md.warn(UnknownKey, md.context.add(k),
+				"Key '%s' doesn't match any field of '%s'.", k, rt.String())
+		}
+	}
 	return nil
 }
 
-func unifyMap(mapping interface{}, rv reflect.Value) error {
+func (md *MetaData) unifyMap(mapping interface{}, rv reflect.Value) error {
 	tmap, ok := mapping.(map[string]interface{})
 	if !ok {
 		return badtype("map", mapping)
@@ -183,8 +508,12 @@ func unifyMap(mapping interface{}, rv reflect.Value) error {
 	for k, v := range tmap {
 		rvkey := indirect(reflect.New(rv.Type().Key()))
 		rvval := indirect(reflect.New(rv.Type().Elem()))
-		if err := unify(v, rvval); err != nil {
-			return err
+		md.decoded[md.context.add(k).String()] = true
+		md.context = append(md.context, k)
+		err := md.unify(v, rvval)
+		md.context = md.context[0 : len(md.context)-1]
+		if err != nil {
+			return md.errorAt(md.context.add(k), err)
 		}
 
 		rvkey.SetString(k)
@@ -193,7 +522,7 @@ func unifyMap(mapping interface{}, rv reflect.Value) error {
 	return nil
 }
 
-func unifySlice(data interface{}, rv reflect.Value) error {
+func (md *MetaData) unifySlice(data interface{}, rv reflect.Value) error {
 	slice, ok := data.([]interface{})
 	if !ok {
 		return badtype("slice", data)
@@ -201,9 +530,22 @@ func unifySlice(data interface{}, rv reflect.Value) error {
 
 	rv.Set(reflect.MakeSlice(rv.Type(), len(slice), len(slice)))
 
+	// The elements of arrays have no keys of their own to find their
+	// literals by.
+	if rv.Type().Elem() == literalType {
+		lit := literal(md.literals, md.context, data)
+		if len(lit.Elements) != len(slice) {
+			lit = literalOf(data)
+		}
+		for i := range slice {
+			rv.Index(i).Set(reflect.ValueOf(lit.Elements[i]))
+		}
+		return nil
+	}
+
 	for i, v := range slice {
 		sliceval := indirect(rv.Index(i))
-		if err := unify(v, sliceval); err != nil {
+		if err := md.unify(v, sliceval); err != nil {
 			return err
 		}
 	}
@@ -226,13 +568,35 @@ func unifyString(data interface{}, rv reflect.Value) error {
 	return badtype("string", data)
 }
 
-func unifyFloat64(data interface{}, rv reflect.Value) error {
+func (md *MetaData) unifyFloat64(data interface{}, rv reflect.Value) error {
+	if num, ok := data.(int64); ok && md.dec.CoerceNumbers {
+		if err := intAsFloat(num, rv.Type()); err != nil {
+			return err
+		}
+		rv.SetFloat(float64(num))
+		return nil
+	}
+	if num, ok := data.(*big.Float); ok {
+		f, err := bigAsFloat(num, rv.Type())
+		if err != nil {
+			return err
+		}
+		md.setFloat(rv, f)
+
+		// A float32 that can't hold `f` has been warned about already.
+		if _, acc := num.Float64(); acc != big.Exact && rv.Float() == f {
+			md.warn(LossyConversion, md.context, "Float %s is rounded to "+
+				"%s to fit %s.", num.Text('g', -1),
+				strconv.FormatFloat(f, 'g', -1, 64), rv.Type())
+		}
+		return nil
+	}
 	if num, ok := data.(float64); ok {
 		switch rv.Kind() {
 		case reflect.Float32:
 			fallthrough
 		case reflect.Float64:
-			rv.SetFloat(num)
+			md.setFloat(rv, num)
 		default:
 			panic("bug")
 		}
@@ -241,7 +605,40 @@ func unifyFloat64(data interface{}, rv reflect.Value) error {
 	return badtype("float", data)
 }
 
-func unifyInt(data interface{}, rv reflect.Value) error {
+// setFloat sets the float `rv` to `num`, warning if it is a float32 that
+// can't hold `num` exactly.
+func (md *MetaData) setFloat(rv reflect.Value, num float64) {
+	rv.SetFloat(num)
+	if rv.Float() != num && !math.IsNaN(num) {
+		md.warn(LossyConversion, md.context, "Float %s is rounded to %s "+
+			"to fit %s.", strconv.FormatFloat(num, 'g', -1, 64),
+			strconv.FormatFloat(rv.Float(), 'g', -1, 64), rv.Type())
+	}
+}
+
+func (md *MetaData) unifyInt(data interface{}, rv reflect.Value) error {
+	if num, ok := data.(float64); ok && md.dec.CoerceNumbers {
+		if err := floatAsInt(num, rv.Type()); err != nil {
+			return err
+		}
+		if rv.Kind() >= reflect.Uint {
+			rv.SetUint(uint64(num))
+		} else {
+			rv.SetInt(int64(num))
+		}
+		return nil
+	}
+	if num, ok := data.(*big.Int); ok {
+		if err := bigAsInt(num, rv.Type()); err != nil {
+			return err
+		}
+		if rv.Kind() >= reflect.Uint {
+			rv.SetUint(num.Uint64())
+		} else {
+			rv.SetInt(num.Int64())
+		}
+		return nil
+	}
 	if num, ok := data.(int64); ok {
 		switch rv.Kind() {
 		case reflect.Int:
@@ -272,20 +669,112 @@ func unifyInt(data interface{}, rv reflect.Value) error {
 	return badtype("integer", data)
 }
 
+// intAsFloat returns an error unless the integer `num` can be represented
+// exactly by the float type `rt`.
+func intAsFloat(num int64, rt reflect.Type) error {
+	f := float64(num)
+	if rt.Kind() == reflect.Float32 {
+		f = float64(float32(num))
+	}
+	// 2^63 itself doesn't fit in an int64, so guard the round trip.
+	if f >= math.MaxInt64 || int64(f) != num {
+		return e("Integer '%d' cannot be represented exactly as a %s.",
+			num, rt)
+	}
+	return nil
+}
+
+// floatAsInt returns an error unless the float `num` is integral and within
+// the range of the integer type `rt`.
+func floatAsInt(num float64, rt reflect.Type) error {
+	if math.IsNaN(num) || math.IsInf(num, 0) || math.Trunc(num) != num {
+		return e("Float '%v' has a fractional part and cannot be "+
+			"converted to %s.", num, rt)
+	}
+	zero := reflect.Zero(rt)
+	if rt.Kind() >= reflect.Uint {
+		if num < 0 || num >= math.MaxUint64 || zero.OverflowUint(uint64(num)) {
+			return e("Float '%v' is out of the range of %s.", num, rt)
+		}
+		return nil
+	}
+	if num < math.MinInt64 || num >= math.MaxInt64 ||
+		zero.OverflowInt(int64(num)) {
+
+		return e("Float '%v' is out of the range of %s.", num, rt)
+	}
+	return nil
+}
+
 func unifyBool(data interface{}, rv reflect.Value) error {
 	if b, ok := data.(bool); ok {
 		rv.SetBool(b)
 		return nil
 	}
-	return badtype("integer", data)
+	return badtype("bool", data)
 }
 
-func unifyAnything(data interface{}, rv reflect.Value) error {
+func (md *MetaData) unifyAnything(data interface{}, rv reflect.Value) error {
 	// too awesome to fail
 	rv.Set(reflect.ValueOf(data))
+
+	// Everything below an empty interface has been consumed.
+	if tmap, ok := data.(map[string]interface{}); ok {
+		md.decodedAll(tmap, md.context)
+	}
 	return nil
 }
 
+func (md *MetaData) unifyUnmarshaler(data interface{}, u Unmarshaler) error {
+	if err := u.UnmarshalTOML(data); err != nil {
+		return md.unmarshalError(err)
+	}
+	if tmap, ok := data.(map[string]interface{}); ok {
+		md.decodedAll(tmap, md.context)
+	}
+	return nil
+}
+
+// unmarshalError returns the error `err` of a type that decodes itself as a
+// *DecodeError, taking its key to be relative to the value being decoded.
+func (md *MetaData) unmarshalError(err error) error {
+	de, ok := err.(*DecodeError)
+	if !ok {
+		return md.errorAt(md.context, err)
+	}
+	key := append(md.context[:len(md.context):len(md.context)], de.Key...)
+	return &DecodeError{
+		Key:      key,
+		Position: md.positions[key.String()],
+		Err:      de.Err,
+	}
+}
+
+func (md *MetaData) unifyPrimitive(data interface{}, rv reflect.Value) error {
+	context := make(Key, len(md.context))
+	copy(context, md.context)
+	rv.Set(reflect.ValueOf(Primitive{
+		undecoded: data,
+		context:   context,
+		position:  md.positions[context.String()],
+		decoded:   md.decoded,
+		positions: md.positions,
+		warnings:  md.warnings,
+	}))
+	return nil
+}
+
+// decodedAll marks every key in `tmap`, recursively, as decoded.
+func (md *MetaData) decodedAll(tmap map[string]interface{}, context Key) {
+	for k, v := range tmap {
+		key := context.add(k)
+		md.decoded[key.String()] = true
+		if t, ok := v.(map[string]interface{}); ok {
+			md.decodedAll(t, key)
+		}
+	}
+}
+
 // rvalue returns a reflect.Value of `v`. All pointers are resolved.
 func rvalue(v interface{}) reflect.Value {
 	return indirect(reflect.ValueOf(v))
@@ -317,18 +806,49 @@ func mismatch(user reflect.Value, expected string, data interface{}) error {
 		tstring(user), expected, data)
 }
 
-func insensitiveGet(
-	tmap map[string]interface{}, kname string) (interface{}, bool) {
+// DecodeError is returned when a TOML value can't be decoded into a Go value.
+// It identifies the most specific key at which decoding failed.
+type DecodeError struct {
+	// The key of the value that couldn't be decoded.
+	Key Key
 
-	if datum, ok := tmap[kname]; ok {
-		return datum, true
+	// Where the key was defined, if known.
+	Position Position
+
+	// The reason decoding failed.
+	Err error
+}
+
+func (de *DecodeError) Error() string {
+	return keyMessage(de.Key, de.Position, de.Err.Error())
+}
+
+// errorAt wraps an error that occurred while unifying the value at `key` in
+// a *DecodeError. Errors that already are one are returned as is, since they
+// point to a more specific key.
+func (md *MetaData) errorAt(key Key, err error) error {
+	if _, ok := err.(*DecodeError); ok {
+		return err
 	}
-	for k, v := range tmap {
-		if strings.EqualFold(kname, k) {
-			return v, true
-		}
+	fullKey := make(Key, len(key))
+	copy(fullKey, key)
+	return &DecodeError{
+		Key:      fullKey,
+		Position: md.positions[fullKey.String()],
+		Err:      md.redactError(fullKey, err),
 	}
-	return nil, false
+}
+
+// keyMessage prefixes a message with the key and position it concerns, as
+// far as they are known.
+func keyMessage(key Key, pos Position, msg string) string {
+	switch {
+	case pos.Line > 0 || len(pos.File) > 0:
+		return fmt.Sprintf("%s, key '%s': %s", pos.near(), key, msg)
+	case len(key) > 0:
+		return fmt.Sprintf("Key '%s': %s", key, msg)
+	}
+	return msg
 }
 
 // MetaData allows access to meta information about TOML data that may not
@@ -340,6 +860,22 @@ type MetaData struct {
 	mapping map[string]interface{}
 	types   map[string]tomlType
 	keys    []Key
+	decoded map[string]bool
+
+	positions map[string]Position
+	literals  map[string]Literal
+
+	// the keys whose values were decoded into secret fields
+	secrets map[string]bool
+
+	// the warnings found while unifying, shared with Primitive values
+	warnings *[]Warning
+
+	// the key of the value currently being unified
+	context Key
+
+	// the decoder whose options apply while unifying
+	dec *Decoder
 }
 
 // IsDefined returns true if the key given exists in the TOML data. The key
@@ -382,6 +918,57 @@ func (md MetaData) Type(key ...string) string {
 	return ""
 }
 
+// Position describes where a key was defined in TOML data.
+type Position struct {
+	// The file the key was defined in. It is empty unless the data was read
+	// with DecodeFile or a Loader. For keys set by an environment variable,
+	// it is the name of the variable prefixed with '$'.
+	File string
+
+	// The line of the key or key group, starting at 1. It is 0 if the key
+	// was created implicitly or its position is otherwise unknown.
+	Line int
+}
+
+// near describes the position for use in error messages.
+func (pos Position) near() string {
+	if len(pos.File) > 0 && pos.Line == 0 {
+		return fmt.Sprintf("In '%s'", pos.File)
+	}
+	if len(pos.File) > 0 {
+		return fmt.Sprintf("Near line %d of '%s'", pos.Line, pos.File)
+	}
+	return fmt.Sprintf("Near line %d", pos.Line)
+}
+
+// Position returns where the key given was defined in the TOML data. Keys
+// are case sensitive.
+func (md MetaData) Position(key ...string) Position {
+	return md.positions[strings.Join(key, ".")]
+}
+
+// Source returns a description of where the value of the key given came
+// from, such as "site.toml:12", or the empty string if the key isn't
+// defined. It is most useful with data merged from several layers by a
+// Loader, where it names the layer that supplied the final value, or the
+// environment variable that overrode it, such as "$APP_DATABASE_SERVER".
+// Keys are case sensitive.
+func (md MetaData) Source(key ...string) string {
+	if !md.IsDefined(key...) {
+		return ""
+	}
+	pos := md.positions[strings.Join(key, ".")]
+	switch {
+	case len(pos.File) > 0 && pos.Line > 0:
+		return fmt.Sprintf("%s:%d", pos.File, pos.Line)
+	case len(pos.File) > 0:
+		return pos.File
+	case pos.Line > 0:
+		return fmt.Sprintf("line %d", pos.Line)
+	}
+	return ""
+}
+
 // Key is the type of any TOML key, including key groups. Use (MetaData).Keys
 // to get values of this type.
 type Key []string
@@ -407,6 +994,19 @@ func (md MetaData) Keys() []Key {
 	return md.keys
 }
 
+// Undecoded returns all keys that have not been decoded into a Go value, in
+// the order in which they appear in the TOML data. Keys inside `Primitive`
+// values are undecoded until the value is passed to `PrimitiveDecode`.
+func (md MetaData) Undecoded() []Key {
+	undecoded := make([]Key, 0)
+	for _, key := range md.keys {
+		if !md.decoded[key.String()] {
+			undecoded = append(undecoded, key)
+		}
+	}
+	return undecoded
+}
+
 func allKeys(m map[string]interface{}, context Key) []Key {
 	keys := make([]Key, 0, len(m))
 	for k, v := range m {
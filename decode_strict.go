@@ -2,7 +2,10 @@ package toml
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math/big"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -11,8 +14,7 @@ var typeOfStringSlice = reflect.TypeOf([]string(nil))
 var typeOfIntSlice = reflect.TypeOf([]int(nil))
 
 func isPointer(v interface{}) bool {
-	vkind := reflect.ValueOf(v).Type().Kind()
-	return vkind == reflect.Ptr
+	return v != nil && reflect.ValueOf(v).Kind() == reflect.Ptr
 }
 
 // Same as PrimitiveDecode but adds a strict verification.
@@ -25,35 +27,73 @@ func PrimitiveDecodeStrict(primValue Primitive,
 		return fmt.Errorf("Must use pointer type for strict decoding: [%s]", v)
 	}
 
-	err = PrimitiveDecode(primValue, v)
+	// Check the data first, so that every violation is reported rather than
+	// the first one that stops the decoder.
+	err = CheckType(primValue, reflect.TypeOf(v).Elem(), ignore_fields)
 	if err != nil {
 		return
 	}
-
-	thestruct := reflect.ValueOf(v).Elem().Interface()
-	return CheckType(primValue, thestruct, ignore_fields)
+	return PrimitiveDecode(primValue, v)
 }
 
 // The same as Decode, except that parsed data that cannot be mapped will
 // throw an error.
+//
+// The keys of `ignore_fields` are patterns of TOML keys that may appear in
+// the data without a corresponding Go value. A pattern is a dotted key, such
+// as `bands.*.type`, where `*` matches any single key component. A pattern
+// with a single component, such as `type`, matches keys of that name at any
+// depth. Patterns are matched case insensitively.
+//
+// When the data doesn't fit `v`, the error returned is a *StrictError that
+// lists every violation found.
 func DecodeStrict(data string,
 	v interface{},
 	ignore_fields map[string]interface{}) (m MetaData, err error) {
 
+	return new(Decoder).decodeStrict(data, v, ignore_fields)
+}
+
+// DecodeStrict is the strict counterpart of (*Decoder).Decode: parsed data
+// that cannot be mapped to `v` will throw an error. See the package level
+// DecodeStrict.
+func (dec *Decoder) DecodeStrict(v interface{},
+	ignore_fields map[string]interface{}) (m MetaData, err error) {
+
+	if dec.r == nil {
+		err = e("Decoder has no reader to decode from.")
+		return
+	}
+	bs, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return
+	}
+	return dec.decodeStrict(string(bs), v, ignore_fields)
+}
+
+func (dec *Decoder) decodeStrict(data string,
+	v interface{},
+	ignore_fields map[string]interface{}) (m MetaData, err error) {
+
 	// Only accept pointer types.
 	if !isPointer(v) {
 		err = fmt.Errorf("Must use pointer type for strict decoding: [%s]", v)
 		return
 	}
 
-	m, err = Decode(data, v)
+	p, err := dec.parse(data, "")
 	if err != nil {
 		return
 	}
 
-	thestruct := reflect.ValueOf(v).Elem().Interface()
-	err = CheckType(m.mapping, thestruct, ignore_fields)
-	return
+	// Check the data first, so that every violation is reported rather than
+	// the first one that stops the decoder.
+	c := newChecker(dec, ignore_fields, p.positions)
+	c.checkTypeStructAsType(p.mapping, reflect.TypeOf(v).Elem(), nil)
+	if err = c.err(); err != nil {
+		return
+	}
+	return dec.unifyParsed(p, v)
 }
 
 func Contains(list []string, elem string) bool {
@@ -65,167 +105,356 @@ func Contains(list []string, elem string) bool {
 	return false
 }
 
+// CheckType verifies that TOML data fits the Go type of `thestruct`, which
+// may be a value or a reflect.Type, without decoding it. See DecodeStrict
+// for the meaning of `ignore_fields` and the errors returned.
 func CheckType(data interface{},
 	thestruct interface{},
 	ignore_fields map[string]interface{}) (err error) {
 
-	var dType reflect.Type
-	var structAsType reflect.Type
-	var structAsTypeOk bool
-	var structAsValue reflect.Value
-	var structAsValueType reflect.Type
+	var context Key
+	var positions map[string]Position
+
+	// Primitive values are checked against their underlying data.
+	if prim, ok := data.(Primitive); ok {
+		data = prim.undecoded
+		context = prim.context
+		positions = prim.positions
+	}
 
-	dType = reflect.TypeOf(data)
+	structAsType, ok := thestruct.(reflect.Type)
+	if !ok {
+		structAsType = reflect.TypeOf(thestruct)
+	}
+	if structAsType == nil {
+		return fmt.Errorf("Cannot check data against a nil type.")
+	}
 
-	structAsType, structAsTypeOk = thestruct.(reflect.Type)
+	c := newChecker(new(Decoder), ignore_fields, positions)
+	c.checkTypeStructAsType(data, structAsType, context)
+	return c.err()
+}
 
-	structAsValue = reflect.ValueOf(thestruct)
-	structAsValueType = structAsValue.Type()
+// Violation is a single way in which TOML data doesn't fit a Go type.
+type Violation struct {
+	// The key of the offending value.
+	Key Key
 
-	// Special case. Go's `time.Time` is a struct, which we don't want
-	// to confuse with a user struct.
-	timeType := rvalue(time.Time{}).Type()
+	// Where the key was defined, if known.
+	Position Position
 
-	if dType == timeType && thestruct == timeType {
+	Message string
+}
+
+func (v Violation) String() string {
+	return keyMessage(v.Key, v.Position, v.Message)
+}
+
+// StrictError is returned by strict decoding when TOML data doesn't fit a Go
+// type. It holds every violation found, ordered by position.
+type StrictError struct {
+	Violations []Violation
+}
+
+func (se *StrictError) Error() string {
+	msgs := make([]string, len(se.Violations))
+	for i, v := range se.Violations {
+		msgs[i] = v.String()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// checker verifies TOML data against a Go type, collecting violations
+// rather than stopping at the first one.
+type checker struct {
+	dec        *Decoder
+	ignore     []Key
+	positions  map[string]Position
+	violations []Violation
+
+	// the keys of secret values, whose values violations don't show
+	secrets map[string]bool
+}
+
+func newChecker(dec *Decoder,
+	ignore_fields map[string]interface{},
+	positions map[string]Position) *checker {
+
+	c := &checker{dec: dec, positions: positions,
+		secrets: make(map[string]bool)}
+	for pattern := range ignore_fields {
+		c.ignore = append(c.ignore, Key(strings.Split(pattern, ".")))
+	}
+	return c
+}
+
+// err returns the violations found so far as a *StrictError, or nil if
+// there are none.
+func (c *checker) err() error {
+	if len(c.violations) == 0 {
 		return nil
 	}
+	sort.SliceStable(c.violations, func(i, j int) bool {
+		return c.violations[i].Position.Line < c.violations[j].Position.Line
+	})
+	return &StrictError{c.violations}
+}
+
+func (c *checker) violation(key Key, format string, v ...interface{}) {
+	c.violations = append(c.violations, Violation{
+		Key:      key,
+		Position: c.positions[key.String()],
+		Message:  fmt.Sprintf(format, v...),
+	})
+}
 
-	if structAsTypeOk {
-		return checkTypeStructAsType(data,
-			structAsType,
-			ignore_fields)
-	} else {
-		return checkTypeStructAsType(data,
-			structAsValueType,
-			ignore_fields)
+// redact removes the secret value `data` of `key` from the messages of the
+// violations at `key` found since the first `from` of them.
+func (c *checker) redact(from int, key Key, data interface{}) {
+	for i := from; i < len(c.violations); i++ {
+		v := &c.violations[i]
+		if v.Key.String() == key.String() {
+			v.Message = redact(v.Message, data, Literal{})
+		}
 	}
 }
 
-func checkTypeStructAsType(data interface{},
+// ignored returns true if `key` matches one of the ignore patterns.
+func (c *checker) ignored(key Key) bool {
+	for _, pattern := range c.ignore {
+		if len(pattern) == 1 {
+			if strings.EqualFold(pattern[0], key[len(key)-1]) {
+				return true
+			}
+			continue
+		}
+		if len(pattern) != len(key) {
+			continue
+		}
+		matched := true
+		for i, p := range pattern {
+			if p != "*" && !strings.EqualFold(p, key[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTypeStructAsType records a violation for every part of `data`, found
+// at `key`, that doesn't fit `structAsType`.
+func (c *checker) checkTypeStructAsType(data interface{},
 	structAsType reflect.Type,
-	ignore_fields map[string]interface{}) (err error) {
+	key Key) {
 
-	dType := reflect.ValueOf(data).Type()
-	dKind := dType.Kind()
+	// Pointers are allocated by the decoder, so check what they point to.
+	for structAsType.Kind() == reflect.Ptr {
+		structAsType = structAsType.Elem()
+	}
 
-	// Handle all the int types
-	dIsInt := (dKind >= reflect.Int && dKind <= reflect.Uint64)
-	sIsInt := (structAsType.Kind() >= reflect.Int && structAsType.Kind() <= reflect.Uint64)
-	if dIsInt && sIsInt {
-		return nil
+	// The values of secrets are kept out of violations.
+	if structAsType == secretType {
+		c.secrets[key.String()] = true
+	}
+	if isSecret(c.secrets, key) {
+		defer c.redact(len(c.violations), key, data)
+	}
+
+	// Values decoded by hooks are checked by running the hooks.
+	if len(c.dec.Hooks) > 0 {
+		v, rest, err := runHooks(c.dec.Hooks, data, structAsType)
+		if err != nil {
+			c.violation(key, "%s", err)
+			return
+		}
+		if v.IsValid() {
+			return
+		}
+		data = rest
+	}
+
+	// Checking Primitive values is delayed until they are decoded.
+	if structAsType == primitiveType {
+		return
+	}
+
+	// Optional values are checked as the values they wrap.
+	if elem := optionalElem(structAsType); elem != nil {
+		c.checkTypeStructAsType(data, elem, key)
+		return
+	}
+
+	// Special case. Go's `time.Time` is a struct, which we don't want
+	// to confuse with a user struct.
+	if structAsType == reflect.TypeOf(time.Time{}) {
+		if _, ok := data.(time.Time); !ok {
+			c.violation(key, "Incoming type didn't match gotype time.Time")
+		}
+		return
+	}
+
+	// A `Literal` value holds how any value but a table was written.
+	if structAsType == literalType {
+		if _, ok := data.(map[string]interface{}); ok {
+			c.violation(key, "Incoming type didn't match gotype %s",
+				structAsType)
+		}
+		return
+	}
+
+	if isBigType(structAsType) {
+		err := unifyBig(data, reflect.New(structAsType).Elem())
+		if err != nil {
+			c.violation(key, "Incoming type didn't match gotype %s",
+				structAsType)
+		}
+		return
 	}
 
 	structKind := structAsType.Kind()
+	if structKind >= reflect.Int && structKind <= reflect.Uint64 {
+		if num, ok := data.(float64); ok && c.dec.CoerceNumbers {
+			if err := floatAsInt(num, structAsType); err != nil {
+				c.violation(key, "%s", err)
+			}
+		} else if num, ok := data.(*big.Int); ok {
+			if err := bigAsInt(num, structAsType); err != nil {
+				c.violation(key, "%s", err)
+			}
+		} else if _, ok := data.(int64); !ok {
+			c.violation(key, "Incoming type didn't match gotype %s",
+				structKind)
+		}
+		return
+	}
+
 	switch structKind {
 	case reflect.Map:
 		dataMap, ok := data.(map[string]interface{})
 		if !ok {
-			return fmt.Errorf("Expected data to be a map: [%s]", data)
+			c.violation(key, "Expected data to be a map: [%v]", data)
+			return
 		}
 
-		// Check the elem, which is the type inside the structAsType
-		// container
-		structMapElem := structAsType.Elem()
-
-		for _, v := range dataMap {
-			// Check each of the items in our dataMap against the
-			// underlying type of the slice type we are mapping onto
-			elemType := structMapElem.(reflect.Type)
-			if err = CheckType(v, elemType, ignore_fields); err != nil {
-				return err
-			}
+		// Check each of the items in our dataMap against the
+		// underlying type of the map type we are mapping onto
+		for _, k := range sortedKeys(dataMap) {
+			c.checkTypeStructAsType(dataMap[k], structAsType.Elem(), key.add(k))
 		}
-		return nil
 	case reflect.Slice:
-		dataSlice := data.([]interface{})
-		// Get the underlying type of the slice in the struct
-		structSliceElem := structAsType.Elem()
+		dataSlice, ok := data.([]interface{})
+		if !ok {
+			c.violation(key, "Expected data to be an array: [%v]", data)
+			return
+		}
+
+		// Check each of the items in our dataSlice against the
+		// underlying type of the slice type we are mapping onto
 		for _, v := range dataSlice {
-			// Check each of the items in our dataslice against the
-			// underlying type of the slice type we are mapping onto
-			elemType := structSliceElem.(reflect.Type)
-			if err = CheckType(v, elemType, ignore_fields); err != nil {
-				return err
-			}
+			c.checkTypeStructAsType(v, structAsType.Elem(), key)
 		}
-		return nil
 	case reflect.String:
-		_, ok := data.(string)
-		if ok {
-			return nil
+		if _, ok := data.(string); !ok {
+			c.violation(key, "Incoming type didn't match gotype string")
 		}
-		return fmt.Errorf("Incoming type didn't match gotype string")
 	case reflect.Bool:
-		_, ok := data.(bool)
-		if ok {
-			return nil
+		if _, ok := data.(bool); !ok {
+			c.violation(key, "Incoming type didn't match gotype bool")
 		}
-		return fmt.Errorf("Incoming type didn't match gotype bool")
 	case reflect.Interface:
-		if structAsType.NumMethod() == 0 {
-			return nil
-		} else {
-			return fmt.Errorf("We don't write data to non-empty interfaces around here")
+		if structAsType.NumMethod() != 0 {
+			c.violation(key,
+				"We don't write data to non-empty interfaces around here")
 		}
 	case reflect.Float32, reflect.Float64:
-		var ok bool
-		_, ok = data.(float32)
-		if ok {
-			return nil
-		}
-		_, ok = data.(float64)
-		if ok {
-			return nil
+		if num, ok := data.(int64); ok && c.dec.CoerceNumbers {
+			if err := intAsFloat(num, structAsType); err != nil {
+				c.violation(key, "%s", err)
+			}
+		} else if num, ok := data.(*big.Float); ok {
+			if _, err := bigAsFloat(num, structAsType); err != nil {
+				c.violation(key, "%s", err)
+			}
+		} else if _, ok := data.(float64); !ok {
+			c.violation(key,
+				"Incoming type didn't match gotype float32/float64")
 		}
-		return fmt.Errorf("Incoming type didn't match gotype float32/float64")
-	case reflect.Array:
-		return fmt.Errorf("*** This shouldn't happen")
 	case reflect.Struct:
-		dataMap := data.(map[string]interface{})
-		// need to iterate over each key in the data to make
-		// sure it exists in structAsType
-		mapKeys := make([]string, 0)
-		for k, _ := range dataMap {
-			mapKeys = append(mapKeys, strings.ToLower(k))
-		}
-		structKeys := make([]string, 0)
-		var fieldName string
-		for i := 0; i < structAsType.NumField(); i++ {
-			f := structAsType.Field(i)
-
-			fieldName = f.Tag.Get("toml")
-			if len(fieldName) == 0 {
-				fieldName = f.Name
+		c.checkStruct(data, structAsType, key)
+	default:
+		c.violation(key, "Unsupported gotype %s", structAsType)
+	}
+}
+
+func (c *checker) checkStruct(data interface{},
+	structAsType reflect.Type,
+	key Key) {
+
+	dataMap, ok := data.(map[string]interface{})
+	if !ok {
+		c.violation(key, "Expected data to be a map: [%v]", data)
+		return
+	}
+
+	// Resolve each struct field to its key in the data the same way
+	// the decoder does, so that keys are matched consistently.
+	fields := structFields(structAsType, c.dec.KeyMapper, c.dec.UseJSONTags)
+	fieldKeys := make([]string, len(fields))
+	used := make(map[string]bool)
+	ix := newKeyIndex(dataMap, !c.dec.NoCaseFolding)
+	for i, f := range fields {
+		// The decoder can't set unexported fields.
+		if len(f.sft.PkgPath) > 0 || f.opts.skip {
+			continue
+		}
+		k, ok, _, err := matchField(ix, f)
+		if err != nil {
+			if len(k) > 0 {
+				used[k] = true
+				c.violation(key.add(k), "%s", err)
+			} else {
+				c.violation(key, "%s", err)
 			}
-			structKeys = append(structKeys, strings.ToLower(fieldName))
+			continue
 		}
-
-		for _, k := range mapKeys {
-			if !Contains(structKeys, k) {
-				if _, ok := insensitiveGet(ignore_fields, k); !ok {
-					return e("Configuration contains key [%s] "+
-						"which doesn't exist in struct", k)
-				}
+		if ok {
+			fieldKeys[i] = k
+			used[k] = true
+			if f.opts.secret {
+				c.secrets[key.add(k).String()] = true
 			}
 		}
+	}
 
-		// Check each struct field against incoming data if
-		// available
-		for i := 0; i < structAsType.NumField(); i++ {
-			f := structAsType.Field(i)
-			fieldName := f.Name
-			mapdata, ok := insensitiveGet(dataMap, fieldName)
-			if ok {
-				err = CheckType(mapdata, f.Type, ignore_fields)
-				if err != nil {
-					return err
-				}
-			}
+	// need to iterate over each key in the data to make
+	// sure it exists in structAsType
+	for _, k := range sortedKeys(dataMap) {
+		if !used[k] && !c.ignored(key.add(k)) {
+			c.violation(key.add(k), "Configuration contains key [%s] "+
+				"which doesn't exist in struct", k)
 		}
-		return nil
-	default:
-		return fmt.Errorf("Unrecognized struct kind: [%s]", structKind)
 	}
 
-	return nil
+	// Check each struct field against incoming data if
+	// available
+	for i, f := range fields {
+		if len(fieldKeys[i]) == 0 {
+			continue
+		}
+		c.checkTypeStructAsType(dataMap[fieldKeys[i]], f.sft.Type,
+			key.add(fieldKeys[i]))
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -350,12 +350,339 @@ albums = ["The J. Geils Band", "Full House", "Blow Your Face Out"]
 		err = PrimitiveDecodeStrict(primValue, &aBand, ignore_type)
 		if artist == "Springsteen" {
 			c.Assume(err, gs.Not(gs.IsNil))
-			c.Expect(err.Error(), gs.Equals, "Configuration contains key [not_albums] which doesn't exist in struct")
-			c.Assume(1973, gs.Equals, aBand.Started)
+			c.Expect(err.Error(), gs.Equals, "Near line 8, key 'bands.Springsteen.not_albums': Configuration contains key [not_albums] which doesn't exist in struct")
+			c.Assume(0, gs.Equals, aBand.Started)
 		} else {
 			c.Expect(err, gs.IsNil)
 			c.Assume(1970, gs.Equals, aBand.Started)
 		}
 
 	}
+
+	// A type mismatch doesn't hide the other violations.
+	var wrong struct {
+		Started string
+	}
+	err = PrimitiveDecodeStrict(music.Bands["Springsteen"], &wrong, nil)
+	se, ok := err.(*StrictError)
+	c.Assume(ok, gs.IsTrue)
+	c.Expect(len(se.Violations), gs.Equals, 4)
+	c.Expect(se.Violations[1].Key.String(), gs.Equals,
+		"bands.Springsteen.started")
+}
+
+func CaseFoldingDecodeSpec(c gs.Context) {
+	var tomlBlob = `
+Port = 1
+port = 2
+host = "localhost"
+`
+
+	c.Specify("exact matches win over case insensitive ones", func() {
+		var val struct {
+			Port int
+			Host string
+		}
+		_, err := Decode(tomlBlob, &val)
+		c.Assume(err, gs.IsNil)
+		c.Expect(val.Port, gs.Equals, 1)
+		c.Expect(val.Host, gs.Equals, "localhost")
+	})
+
+	c.Specify("several case insensitive matches are ambiguous", func() {
+		var val struct {
+			PORT int
+		}
+		_, err := Decode(tomlBlob, &val)
+		c.Assume(err, gs.Not(gs.IsNil))
+		c.Expect(strings.Contains(err.Error(),
+			"Keys 'Port', 'port' all match 'PORT'"), gs.IsTrue)
+	})
+
+	c.Specify("case folding can be disabled", func() {
+		var val struct {
+			PORT int
+			HOST string
+		}
+		dec := NewDecoder(strings.NewReader(tomlBlob))
+		dec.NoCaseFolding = true
+		_, err := dec.Decode(&val)
+		c.Assume(err, gs.IsNil)
+		c.Expect(val.PORT, gs.Equals, 0)
+		c.Expect(val.HOST, gs.Equals, "")
+	})
+
+	c.Specify("strict decoding matches keys like Decode", func() {
+		var val struct {
+			Port int
+			Host string
+		}
+		_, err := DecodeStrict(tomlBlob, &val, map[string]interface{}{})
+		c.Assume(err, gs.Not(gs.IsNil))
+		c.Expect(err.Error(), gs.Equals, "Near line 3, key 'port': "+
+			"Configuration contains key [port] which doesn't exist in struct")
+	})
+}
+
+func KeyMapperDecodeSpec(c gs.Context) {
+	var tomlBlob = `
+connection_max = 5000
+server-name = "alpha"
+
+[http_server]
+listen_addr = "localhost"
+`
+
+	type httpServer struct {
+		ListenAddr string
+	}
+
+	c.Specify("snake case keys match untagged fields", func() {
+		var val struct {
+			ConnectionMax int
+			ServerName    string `toml:"server-name"`
+			HTTPServer    httpServer
+		}
+		dec := NewDecoder(strings.NewReader(tomlBlob))
+		dec.KeyMapper = SnakeCase
+		_, err := dec.DecodeStrict(&val, map[string]interface{}{})
+		c.Assume(err, gs.IsNil)
+		c.Expect(val.ConnectionMax, gs.Equals, 5000)
+		c.Expect(val.ServerName, gs.Equals, "alpha")
+		c.Expect(val.HTTPServer.ListenAddr, gs.Equals, "localhost")
+	})
+
+	c.Specify("kebab case keys match untagged fields", func() {
+		var val struct {
+			ServerName string
+		}
+		dec := NewDecoder(strings.NewReader(tomlBlob))
+		dec.KeyMapper = KebabCase
+		_, err := dec.Decode(&val)
+		c.Assume(err, gs.IsNil)
+		c.Expect(val.ServerName, gs.Equals, "alpha")
+	})
+}
+
+func JSONTagsDecodeSpec(c gs.Context) {
+	var tomlBlob = `
+server_name = "alpha"
+loc = "Toronto"
+password = "hunter2"
+`
+
+	type shared struct {
+		ServerName string `json:"server_name"`
+		Location   string `json:"loc,omitempty" toml:"location"`
+		Password   string `json:"-"`
+	}
+
+	c.Specify("json tags are ignored by default", func() {
+		var val shared
+		_, err := Decode(tomlBlob, &val)
+		c.Assume(err, gs.IsNil)
+		c.Expect(val.ServerName, gs.Equals, "")
+		c.Expect(val.Password, gs.Equals, "hunter2")
+	})
+
+	c.Specify("json tags are used when toml tags are absent", func() {
+		var val shared
+		dec := NewDecoder(strings.NewReader(tomlBlob))
+		dec.UseJSONTags = true
+		_, err := dec.Decode(&val)
+		c.Assume(err, gs.IsNil)
+		c.Expect(val.ServerName, gs.Equals, "alpha")
+		c.Expect(val.Location, gs.Equals, "")
+		c.Expect(val.Password, gs.Equals, "")
+	})
+
+	c.Specify("strict decoding honours json tags", func() {
+		var val shared
+		dec := NewDecoder(strings.NewReader(tomlBlob))
+		dec.UseJSONTags = true
+		_, err := dec.DecodeStrict(&val, map[string]interface{}{})
+		c.Assume(err, gs.Not(gs.IsNil))
+		c.Expect(err.Error(), gs.Equals, "Near line 3, key 'loc': "+
+			"Configuration contains key [loc] which doesn't exist in struct\n"+
+			"Near line 4, key 'password': "+
+			"Configuration contains key [password] which doesn't exist in struct")
+	})
+}
+
+func UndecodedSpec(c gs.Context) {
+	var tomlBlob = `
+ranking = ["Springsteen", "J Geils"]
+unknown = true
+
+[bands.Springsteen]
+started = 1973
+albums = ["Greetings", "WIESS", "Born to Run", "Darkness"]
+
+[bands.J Geils]
+started = 1970
+albums = ["The J. Geils Band", "Full House", "Blow Your Face Out"]
+`
+
+	type band struct {
+		Started int
+	}
+
+	type classics struct {
+		Ranking []string
+		Bands   map[string]Primitive
+	}
+
+	keyStrings := func(keys []Key) []string {
+		strs := make([]string, len(keys))
+		for i, key := range keys {
+			strs[i] = key.String()
+		}
+		return strs
+	}
+
+	var music classics
+	md, err := Decode(tomlBlob, &music)
+	c.Assume(err, gs.IsNil)
+
+	c.Expect(keyStrings(md.Undecoded()), gs.Equals, []string{
+		"unknown",
+		"bands.Springsteen.started",
+		"bands.Springsteen.albums",
+		"bands.J Geils.started",
+		"bands.J Geils.albums",
+	})
+
+	var aBand band
+	err = PrimitiveDecode(music.Bands["Springsteen"], &aBand)
+	c.Assume(err, gs.IsNil)
+	c.Expect(keyStrings(md.Undecoded()), gs.Equals, []string{
+		"unknown",
+		"bands.Springsteen.albums",
+		"bands.J Geils.started",
+		"bands.J Geils.albums",
+	})
+
+	var anything interface{}
+	err = PrimitiveDecode(music.Bands["J Geils"], &anything)
+	c.Assume(err, gs.IsNil)
+	c.Expect(keyStrings(md.Undecoded()), gs.Equals, []string{
+		"unknown",
+		"bands.Springsteen.albums",
+	})
+}
+
+func PrimitiveContextSpec(c gs.Context) {
+	var tomlBlob = `
+[plugins.tcp]
+listen_addr = "localhost:5565"
+
+[plugins.udp]
+listen_addr = 5565
+`
+
+	type plugin struct {
+		ListenAddr string
+	}
+
+	var plugins struct {
+		Plugins map[string]Primitive
+	}
+	dec := NewDecoder(strings.NewReader(tomlBlob))
+	dec.KeyMapper = SnakeCase
+	md, err := dec.Decode(&plugins)
+	c.Assume(err, gs.IsNil)
+
+	c.Specify("primitive values know their key and position", func() {
+		prim := plugins.Plugins["udp"]
+		c.Expect(prim.Key().String(), gs.Equals, "plugins.udp")
+		c.Expect(prim.Position().Line, gs.Equals, 5)
+	})
+
+	c.Specify("meta data decodes primitives with the decoder options", func() {
+		var p plugin
+		err := md.PrimitiveDecode(plugins.Plugins["tcp"], &p)
+		c.Assume(err, gs.IsNil)
+		c.Expect(p.ListenAddr, gs.Equals, "localhost:5565")
+		c.Expect(len(md.Undecoded()), gs.Equals, 1)
+	})
+
+	c.Specify("errors in deferred decoding name the key", func() {
+		var p plugin
+		err := md.PrimitiveDecode(plugins.Plugins["udp"], &p)
+		c.Assume(err, gs.Not(gs.IsNil))
+		de, ok := err.(*DecodeError)
+		c.Assume(ok, gs.IsTrue)
+		c.Expect(de.Key.String(), gs.Equals, "plugins.udp.listen_addr")
+		c.Expect(de.Position.Line, gs.Equals, 6)
+		c.Expect(err.Error(), gs.Equals,
+			"Near line 6, key 'plugins.udp.listen_addr': Type mismatch for "+
+				"'toml.plugin.ListenAddr': Expected string but found 'int64'.")
+	})
+}
+
+func NumericCoercionSpec(c gs.Context) {
+	type numbers struct {
+		Ratio   float64
+		Workers int
+		Small   int8
+	}
+
+	decode := func(blob string, coerce bool) (numbers, error) {
+		var val numbers
+		dec := NewDecoder(strings.NewReader(blob))
+		dec.CoerceNumbers = coerce
+		_, err := dec.DecodeStrict(&val, nil)
+		return val, err
+	}
+
+	c.Specify("numbers are not coerced by default", func() {
+		_, err := decode("ratio = 1", false)
+		c.Expect(err, gs.Not(gs.IsNil))
+		_, err = decode("workers = 3.0", false)
+		c.Expect(err, gs.Not(gs.IsNil))
+	})
+
+	c.Specify("lossless conversions are allowed", func() {
+		val, err := decode("ratio = 1\nworkers = 3.0\nsmall = -128.0", true)
+		c.Assume(err, gs.IsNil)
+		c.Expect(val.Ratio, gs.Equals, 1.0)
+		c.Expect(val.Workers, gs.Equals, 3)
+		c.Expect(val.Small, gs.Equals, int8(-128))
+	})
+
+	c.Specify("lossy conversions are errors", func() {
+		_, err := decode("workers = 3.5", true)
+		c.Assume(err, gs.Not(gs.IsNil))
+		c.Expect(strings.Contains(err.Error(),
+			"Float '3.5' has a fractional part"), gs.IsTrue)
+
+		_, err = decode("small = 300.0", true)
+		c.Assume(err, gs.Not(gs.IsNil))
+		c.Expect(strings.Contains(err.Error(),
+			"Float '300' is out of the range of int8"), gs.IsTrue)
+
+		_, err = decode("ratio = 9007199254740993", true)
+		c.Assume(err, gs.Not(gs.IsNil))
+		c.Expect(strings.Contains(err.Error(),
+			"cannot be represented exactly as a float64"), gs.IsTrue)
+	})
+
+	c.Specify("strict checking mirrors the decoder", func() {
+		data := map[string]interface{}{"ratio": int64(1), "workers": 3.0}
+		dec := NewDecoder(nil)
+		ck := newChecker(dec, nil, nil)
+		ck.checkTypeStructAsType(data, reflect.TypeOf(numbers{}), nil)
+		c.Expect(len(ck.violations), gs.Equals, 2)
+
+		dec.CoerceNumbers = true
+		ck = newChecker(dec, nil, nil)
+		ck.checkTypeStructAsType(data, reflect.TypeOf(numbers{}), nil)
+		c.Expect(ck.err(), gs.IsNil)
+
+		data["workers"] = 3.5
+		ck = newChecker(dec, nil, nil)
+		ck.checkTypeStructAsType(data, reflect.TypeOf(numbers{}), nil)
+		c.Expect(ck.err().Error(), gs.Equals, "Key 'workers': "+
+			"Float '3.5' has a fractional part and cannot be converted to int.")
+	})
 }
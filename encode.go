@@ -17,57 +17,308 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
+	"sort"
 	"strings"
 )
 
-type encoder struct {
+// Encoder writes Go values to an output stream as TOML.
+type Encoder struct {
 	// A single indentation level. By default it is two spaces.
 	Indent string
 
+	// KeyMapper, when set, maps the names of struct fields without a `toml`
+	// tag to TOML keys. It should match the one used to decode the data.
+	KeyMapper KeyMapper
+
+	// UseJSONTags makes fields without a `toml` tag use the name and options
+	// of their `json` tag instead, if they have one.
+	UseJSONTags bool
+
+	// RedactSecrets makes the encoder write asterisks in place of the values
+	// of Secret values and fields with the `secret` option, e.g., to log a
+	// configuration. A secret field is written as a single string, even if
+	// it holds a table.
+	RedactSecrets bool
+
+	// IgnoreMarshalers makes the encoder use reflection for every value,
+	// even if its type implements Marshaler.
+	IgnoreMarshalers bool
+
 	w *bufio.Writer
+
+	// whether anything has been written, so that a table header needs a
+	// blank line before it
+	wrote bool
+}
+
+// Marshaler is implemented by types that encode themselves, such as those
+// that the tomlgen command generates code for. MarshalTOML returns the value
+// in the form that Unmarshaler takes. Tables are written with their keys in
+// sorted order.
+type Marshaler interface {
+	MarshalTOML() (interface{}, error)
 }
 
-func newEncoder(w io.Writer) *encoder {
-	return &encoder{
+var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+
+// NewEncoder returns a new encoder that writes to `w`.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
 		w:      bufio.NewWriter(w),
 		Indent: "  ",
 	}
 }
 
-func (enc *encoder) Encode(v interface{}) error {
-	rv := eindirect(reflect.ValueOf(v))
-	if err := enc.encode(Key([]string{}), rv); err != nil {
+// Encode writes the TOML representation of `v` to the encoder's stream.
+// Values are written before tables, and the keys of each table are written
+// in the order of the fields of its struct, or in sorted order for maps.
+func (enc *Encoder) Encode(v interface{}) error {
+	val, err := enc.value(Key{}, eindirect(reflect.ValueOf(v)))
+	if err != nil {
+		return err
+	}
+	table, ok := val.(*eTable)
+	if !ok {
+		return e("Cannot encode '%T', since only tables can be written at "+
+			"the top level.", v)
+	}
+	if err := enc.eTable(Key{}, table); err != nil {
 		return err
 	}
 	return enc.w.Flush()
 }
 
-func (enc *encoder) encode(key Key, rv reflect.Value) error {
-	k := rv.Kind()
-	switch k {
+// eTable is a table to be written: its keys, in the order they are written,
+// and their values.
+type eTable struct {
+	keys []string
+	vals map[string]interface{}
+}
+
+func (t *eTable) add(k string, val interface{}) {
+	t.keys = append(t.keys, k)
+	t.vals[k] = val
+}
+
+// eRaw is the text of a value, written as it is.
+type eRaw string
+
+// value converts `rv`, found at `key`, into the TOML value to be written:
+// an *eTable, an eRaw, or a value the parser produces other than a table.
+// It returns nil if there is nothing to write, such as for a nil pointer.
+func (enc *Encoder) value(key Key, rv reflect.Value) (interface{}, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	// An `Optional` value is encoded as the value it wraps, or not at all
+	// if it is absent.
+	if optionalElem(rv.Type()) != nil && rv.CanInterface() {
+		opt := reflect.New(rv.Type())
+		opt.Elem().Set(rv)
+		v, ok := opt.Interface().(optional).getValue()
+		if !ok {
+			return nil, nil
+		}
+		rv = v
+	}
+
+	if enc.RedactSecrets && rv.Type() == secretType {
+		return eRaw(quote(redacted)), nil
+	}
+
+	// A `Literal` value is written as it was read.
+	if rv.Type() == literalType {
+		lit := rv.Interface().(Literal)
+		if lit.Style == 0 {
+			return nil, nil
+		}
+		return eRaw(lit.Text), nil
+	}
+
+	// Types that encode themselves are left to do so.
+	if m, ok := marshaler(rv); ok && !enc.IgnoreMarshalers {
+		data, err := m.MarshalTOML()
+		if err != nil {
+			return nil, err
+		}
+		return enc.value(key, reflect.ValueOf(data))
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return enc.value(key, rv.Elem())
 	case reflect.Struct:
-		return enc.eStruct(key, rv)
-	case reflect.String:
-		return enc.eString(key, rv)
+		if rv.Type() != timeType {
+			return enc.eStruct(key, rv)
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			return enc.eMap(key, rv)
+		}
+	case reflect.Slice, reflect.Array:
+		return enc.eArray(key, rv)
 	}
-	return e("Unsupported type for key '%s': %s", key, k)
+	val, err := treeValue(rv)
+	if err != nil {
+		return nil, e("Cannot encode key '%s': %s", key, err)
+	}
+	return val, nil
 }
 
-func (enc *encoder) eStruct(key Key, rv reflect.Value) error {
+func (enc *Encoder) eStruct(key Key, rv reflect.Value) (*eTable, error) {
 	rt := rv.Type()
-	for i := 0; i < rt.NumField(); i++ {
-		sft := rt.Field(i)
-		sf := rv.Field(i)
-		if err := enc.encode(key.add(sft.Name), sf); err != nil {
+	fields := structFields(rt, enc.KeyMapper, enc.UseJSONTags)
+	table := &eTable{vals: make(map[string]interface{}, len(fields))}
+	for i, f := range fields {
+		sf, opts := rv.Field(i), f.opts
+		if opts.skip || len(f.sft.PkgPath) > 0 ||
+			(opts.omitempty && isEmpty(sf)) {
+
+			continue
+		}
+		if enc.RedactSecrets && opts.secret {
+			table.add(opts.name, eRaw(quote(redacted)))
+			continue
+		}
+		val, err := enc.value(key.add(opts.name), sf)
+		if err != nil {
+			return nil, err
+		}
+		if val != nil {
+			table.add(opts.name, val)
+		}
+	}
+	return table, nil
+}
+
+func (enc *Encoder) eMap(key Key, rv reflect.Value) (*eTable, error) {
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+
+	table := &eTable{vals: make(map[string]interface{}, len(keys))}
+	for _, k := range keys {
+		mk := reflect.ValueOf(k).Convert(rv.Type().Key())
+		val, err := enc.value(key.add(k), rv.MapIndex(mk))
+		if err != nil {
+			return nil, err
+		}
+		if val != nil {
+			table.add(k, val)
+		}
+	}
+	return table, nil
+}
+
+func (enc *Encoder) eArray(key Key, rv reflect.Value) ([]interface{}, error) {
+	array := make([]interface{}, rv.Len())
+	for i := range array {
+		val, err := enc.value(key, rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		switch val.(type) {
+		case nil:
+			return nil, e("Cannot encode key '%s': TOML has no null value.",
+				key)
+		case *eTable:
+			return nil, e("Cannot encode key '%s': TOML has no arrays of "+
+				"tables.", key)
+		}
+		_, raw := val.(eRaw)
+		_, rawFirst := array[0].(eRaw)
+		if i > 0 && !raw && !rawFirst &&
+			!typeEqual(typeOfValue(array[0]), typeOfValue(val)) {
+
+			return nil, e("Cannot encode key '%s': Array contains values "+
+				"of type '%s' and '%s', but arrays must be homogeneous.",
+				key, typeOfValue(array[0]), typeOfValue(val))
+		}
+		array[i] = val
+	}
+	return array, nil
+}
+
+// marshaler returns `rv` as a Marshaler, if it or a pointer to it is one.
+func marshaler(rv reflect.Value) (Marshaler, bool) {
+	if !rv.CanInterface() ||
+		!reflect.PtrTo(rv.Type()).Implements(marshalerType) {
+
+		return nil, false
+	}
+	if !rv.CanAddr() {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		rv = ptr.Elem()
+	}
+	m, ok := rv.Addr().Interface().(Marshaler)
+	return m, ok
+}
+
+// eTable writes the table `t` at `key`. Its values are written first, since
+// TOML takes the values after a table header to be in that table.
+func (enc *Encoder) eTable(key Key, t *eTable) error {
+	for _, k := range t.keys {
+		if _, ok := t.vals[k].(*eTable); ok {
+			continue
+		}
+		text, err := eText(key.add(k), t.vals[k])
+		if err != nil {
+			return err
+		}
+		if err := enc.eKeyVal(key.add(k), text); err != nil {
+			return err
+		}
+	}
+	for _, k := range t.keys {
+		sub, ok := t.vals[k].(*eTable)
+		if !ok {
+			continue
+		}
+		if err := enc.eHeader(key.add(k)); err != nil {
+			return err
+		}
+		if err := enc.eTable(key.add(k), sub); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (enc *encoder) eString(key Key, rv reflect.Value) error {
-	s := rv.String()
+// eText returns the text that the value `val` at `key` is written as.
+func eText(key Key, val interface{}) (string, error) {
+	switch val := val.(type) {
+	case eRaw:
+		return string(val), nil
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return "", e("Cannot encode key '%s': TOML has no float %v.",
+				key, val)
+		}
+	case []interface{}:
+		texts := make([]string, len(val))
+		for i, elem := range val {
+			text, err := eText(key, elem)
+			if err != nil {
+				return "", err
+			}
+			texts[i] = text
+		}
+		return "[" + strings.Join(texts, ", ") + "]", nil
+	}
+	return literalOf(val).Text, nil
+}
+
+// quote returns `s` as a TOML string in double quotes.
+func quote(s string) string {
 	s = strings.NewReplacer(
 		"\t", "\\t",
 		"\n", "\\n",
@@ -75,14 +326,29 @@ func (enc *encoder) eString(key Key, rv reflect.Value) error {
 		"\"", "\\\"",
 		"\\", "\\\\",
 	).Replace(s)
-	s = "\"" + s + "\""
-	if err := enc.eKeyVal(key, s); err != nil {
+	return "\"" + s + "\""
+}
+
+func (enc *Encoder) eHeader(key Key) error {
+	if err := checkKey(key); err != nil {
 		return err
 	}
-	return nil
+	if enc.wrote {
+		if _, err := fmt.Fprintln(enc.w); err != nil {
+			return err
+		}
+	}
+	enc.wrote = true
+	_, err := fmt.Fprintf(enc.w, "%s[%s]\n",
+		strings.Repeat(enc.Indent, len(key)-1), strings.Join(key, "."))
+	return err
 }
 
-func (enc *encoder) eKeyVal(key Key, value string) error {
+func (enc *Encoder) eKeyVal(key Key, value string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	enc.wrote = true
 	out := fmt.Sprintf("%s%s = %s",
 		strings.Repeat(enc.Indent, len(key)-1), key[len(key)-1], value)
 	if _, err := fmt.Fprintln(enc.w, out); err != nil {
@@ -91,6 +357,42 @@ func (enc *encoder) eKeyVal(key Key, value string) error {
 	return nil
 }
 
+// checkKey returns an error if a part of `key` can't be written in TOML,
+// which has no quoted keys.
+func checkKey(key Key) error {
+	for _, k := range key {
+		if len(k) == 0 || strings.ContainsAny(k, " \t\r\n=.[]") ||
+			k[0] == '#' {
+
+			return e("Cannot encode key '%s': TOML keys cannot be empty, "+
+				"start with '#', or contain whitespace, '=', '.', '[' or "+
+				"']'.", key)
+		}
+	}
+	return nil
+}
+
+// isEmpty reports whether `rv` holds the zero value of its kind, in the same
+// sense as the `omitempty` option of encoding/json.
+func isEmpty(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	}
+	return false
+}
+
 func eindirect(v reflect.Value) reflect.Value {
 	if v.Kind() != reflect.Ptr {
 		return v
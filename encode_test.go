@@ -2,7 +2,11 @@ package toml
 
 import (
 	"bytes"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type encodeSimple struct {
@@ -17,9 +21,192 @@ func TestEncode(t *testing.T) {
 	}
 
 	buf := new(bytes.Buffer)
-	e := newEncoder(buf)
+	e := NewEncoder(buf)
 	if err := e.Encode(v); err != nil {
 		t.Fatal(err)
 	}
 	testf(buf.String())
 }
+
+func TestEncodeKeyMapper(t *testing.T) {
+	v := struct {
+		ServerName string
+		Tagged     string `toml:"Tagged_Name"`
+	}{"alpha", "beta"}
+
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	e.KeyMapper = SnakeCase
+	if err := e.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	expected := "server_name = \"alpha\"\nTagged_Name = \"beta\"\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q but got %q", expected, buf.String())
+	}
+}
+
+func TestEncodeJSONTags(t *testing.T) {
+	v := struct {
+		Name     string `json:"name"`
+		Nickname string `json:"nickname,omitempty"`
+		Password string `json:"-"`
+		Location string `json:"loc" toml:"location"`
+	}{Name: "andrew", Password: "secret", Location: "Boston"}
+
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	e.UseJSONTags = true
+	if err := e.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	expected := "name = \"andrew\"\nlocation = \"Boston\"\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q but got %q", expected, buf.String())
+	}
+}
+
+type upperString string
+
+func (s *upperString) MarshalTOML() (interface{}, error) {
+	return strings.ToUpper(string(*s)), nil
+}
+
+type marshalTable struct{ B, A string }
+
+func (t *marshalTable) MarshalTOML() (interface{}, error) {
+	return map[string]interface{}{"b": t.B, "a": t.A}, nil
+}
+
+func TestEncodeMarshaler(t *testing.T) {
+	v := struct {
+		Name  upperString
+		Table marshalTable
+	}{"andrew", marshalTable{"x", "y"}}
+
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Name = \"ANDREW\"\n\n[Table]\n  a = \"y\"\n  b = \"x\"\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q but got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	e := NewEncoder(buf)
+	e.IgnoreMarshalers = true
+	if err := e.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	expected = "Name = \"andrew\"\n\n[Table]\n  B = \"x\"\n  A = \"y\"\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q but got %q", expected, buf.String())
+	}
+}
+
+type encodeNested struct {
+	Title string
+	Owner struct {
+		Name string
+		DOB  time.Time
+	}
+	Database struct {
+		Ports   []int
+		Ratio   float64
+		Enabled bool
+	}
+	Servers map[string]struct{ IP string }
+	Matrix  [][]interface{}
+	Count   uint8
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	var v encodeNested
+	v.Title = "TOML \"Example\""
+	v.Owner.Name = "Tom"
+	v.Owner.DOB = time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)
+	v.Database.Ports = []int{8001, 8002}
+	v.Database.Ratio = 2
+	v.Database.Enabled = true
+	v.Servers = map[string]struct{ IP string }{
+		"beta":  {"10.0.0.2"},
+		"alpha": {"10.0.0.1"},
+	}
+	v.Matrix = [][]interface{}{{"a", "b"}, {int64(1)}}
+	v.Count = 3
+
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Title = "TOML \"Example\""
+Matrix = [["a", "b"], [1]]
+Count = 3
+
+[Owner]
+  Name = "Tom"
+  DOB = 1979-05-27T07:32:00Z
+
+[Database]
+  Ports = [8001, 8002]
+  Ratio = 2.0
+  Enabled = true
+
+[Servers]
+
+  [Servers.alpha]
+    IP = "10.0.0.1"
+
+  [Servers.beta]
+    IP = "10.0.0.2"
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+
+	var decoded encodeNested
+	if _, err := Decode(buf.String(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, decoded) {
+		t.Fatalf("Expected %+v to round trip, but got %+v", v, decoded)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{
+			struct{ Mixed []interface{} }{[]interface{}{1, "a"}},
+			"Cannot encode key 'Mixed': Array contains values of type " +
+				"'Integer' and 'String', but arrays must be homogeneous.",
+		},
+		{
+			struct{ Servers []struct{ IP string } }{[]struct{ IP string }{{}}},
+			"Cannot encode key 'Servers': TOML has no arrays of tables.",
+		},
+		{
+			map[string]float64{"nan": math.NaN()},
+			"Cannot encode key 'nan': TOML has no float NaN.",
+		},
+		{
+			map[string]int{"a b": 1},
+			"Cannot encode key 'a b': TOML keys cannot be empty, start " +
+				"with '#', or contain whitespace, '=', '.', '[' or ']'.",
+		},
+		{
+			"title",
+			"Cannot encode 'string', since only tables can be written at " +
+				"the top level.",
+		},
+	}
+	for _, test := range tests {
+		err := NewEncoder(new(bytes.Buffer)).Encode(test.v)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Expected %q but got %v", test.want, err)
+		}
+	}
+}
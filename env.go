@@ -0,0 +1,243 @@
+package toml
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type envLayer struct {
+	prefix, sep string
+}
+
+// EnvLayer returns a layer of values taken from the environment variables
+// whose names start with `prefix` followed by `sep`, such as
+// APP_DATABASE_CONNECTION_MAX for the prefix "APP" and the separator "_".
+// The separator defaults to "_".
+//
+// The rest of a name is split at each separator and matched case
+// insensitively against the keys of the earlier layers and the fields of the
+// struct being loaded into, so the variable above overrides the key
+// `database.connection_max`. Where a name can be split in several ways, the
+// longest matching key wins. In tables that are neither defined by earlier
+// layers nor backed by a struct, each part of the name is taken as a key in
+// lower case. Variables that match no key are ignored.
+//
+// Values are parsed as TOML values, so `100`, `true` and `[1, 2]` get the
+// types one would expect. A value that isn't valid TOML, or that is decoded
+// into a Go string, is taken as a string. Values are never expanded nor
+// taken as include directives, whatever the Decoder's ExpandEnv and
+// IncludeKey.
+//
+// The source of each overridden key, as reported by (MetaData).Source, is
+// the name of the variable prefixed with '$'.
+func EnvLayer(prefix, sep string) Layer {
+	if len(sep) == 0 {
+		sep = "_"
+	}
+	return envLayer{prefix, sep}
+}
+
+func (l envLayer) parse(
+	dec *Decoder, merged *parser, rt reflect.Type) (*parser, error) {
+
+	env := os.Environ()
+	sort.Strings(env)
+
+	// Variables may come from anywhere, so their values mustn't be able to
+	// read files or other variables.
+	valueDec := *dec
+	valueDec.IncludeKey, valueDec.ExpandEnv = "", false
+
+	var p *parser
+	for _, kv := range env {
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			continue
+		}
+		name, raw := kv[:i], kv[i+1:]
+		rest, ok := l.trimPrefix(name)
+		if !ok {
+			continue
+		}
+		key, vt := l.resolve(dec, merged.mapping, rt,
+			strings.Split(rest, l.sep))
+		if key == nil {
+			continue
+		}
+
+		if p == nil {
+			p = newLayerParser()
+		}
+		pos := Position{File: "$" + name}
+		val, typ := envValue(&valueDec, raw, vt, pos)
+		if err := setKey(p, key, val, typ, pos); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// trimPrefix returns the part of the variable name `name` following the
+// prefix and separator, and whether the name has them at all.
+func (l envLayer) trimPrefix(name string) (string, bool) {
+	if len(l.prefix) == 0 {
+		return name, len(name) > 0
+	}
+	lead := l.prefix + l.sep
+	if len(name) <= len(lead) || !strings.EqualFold(name[:len(lead)], lead) {
+		return "", false
+	}
+	return name[len(lead):], true
+}
+
+// envKey is a key that a part of a variable name may refer to, along with
+// the Go type its value is decoded into, if known, and the other names of
+// the struct field it sets, if any.
+type envKey struct {
+	name    string
+	rt      reflect.Type
+	aliases []string
+}
+
+// matches returns true if `want` is one of the names of the key, case
+// insensitively.
+func (k envKey) matches(want string) bool {
+	if strings.EqualFold(k.name, want) {
+		return true
+	}
+	for _, alias := range k.aliases {
+		if strings.EqualFold(alias, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve finds the key named by the parts of a variable name `parts` in
+// the table `table`, which is decoded into the type `rt`. It returns a nil
+// key if there is none, and otherwise the Go type of the key's value.
+func (l envLayer) resolve(dec *Decoder, table map[string]interface{},
+	rt reflect.Type, parts []string) (Key, reflect.Type) {
+
+	rt = envElem(rt)
+	if !envTable(rt) {
+		return nil, nil
+	}
+
+	// Keys already defined come first, so they win over field names that
+	// differ only in case.
+	var fields []envKey
+	if rt != nil && rt.Kind() == reflect.Struct {
+		for _, f := range structFields(rt, dec.KeyMapper, dec.UseJSONTags) {
+			if len(f.sft.PkgPath) == 0 && !f.opts.skip {
+				fields = append(fields,
+					envKey{f.opts.name, f.sft.Type, f.opts.aliases})
+			}
+		}
+	}
+	var cands []envKey
+	for _, k := range sortedKeys(table) {
+		cand := envKey{name: k}
+		switch {
+		case rt == nil || rt.Kind() == reflect.Interface:
+		case rt.Kind() == reflect.Map:
+			cand.rt = rt.Elem()
+		default:
+			for _, f := range fields {
+				if f.matches(k) {
+					cand.rt = f.rt
+					cand.aliases = append([]string{f.name}, f.aliases...)
+					break
+				}
+			}
+		}
+		cands = append(cands, cand)
+	}
+	cands = append(cands, fields...)
+
+	for i := len(parts); i > 0; i-- {
+		want := strings.Join(parts[:i], l.sep)
+		for _, cand := range cands {
+			if !cand.matches(want) {
+				continue
+			}
+			if i == len(parts) {
+				return Key{cand.name}, cand.rt
+			}
+			sub, _ := table[cand.name].(map[string]interface{})
+			if key, vt := l.resolve(dec, sub, cand.rt, parts[i:]); key != nil {
+				return append(Key{cand.name}, key...), vt
+			}
+		}
+	}
+
+	// Tables that aren't backed by a struct may hold any key.
+	if rt != nil && rt.Kind() == reflect.Struct {
+		return nil, nil
+	}
+	var elem reflect.Type
+	if rt != nil && rt.Kind() == reflect.Map {
+		elem = rt.Elem()
+	}
+	name := strings.ToLower(parts[0])
+	if len(parts) == 1 {
+		return Key{name}, elem
+	}
+	sub, _ := table[name].(map[string]interface{})
+	if key, vt := l.resolve(dec, sub, elem, parts[1:]); key != nil {
+		return append(Key{name}, key...), vt
+	}
+	return nil, nil
+}
+
+// envElem returns the type that values of type `rt` are decoded as, or nil
+// if it isn't known.
+func envElem(rt reflect.Type) reflect.Type {
+	for rt != nil {
+		if rt == primitiveType {
+			return nil
+		}
+		if elem := optionalElem(rt); elem != nil {
+			rt = elem
+		} else if rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		} else {
+			break
+		}
+	}
+	return rt
+}
+
+// envTable returns true if values of type `rt` may be decoded from a table.
+func envTable(rt reflect.Type) bool {
+	if rt == nil {
+		return true
+	}
+	switch rt.Kind() {
+	case reflect.Map, reflect.Interface:
+		return true
+	case reflect.Struct:
+		return rt != reflect.TypeOf(time.Time{})
+	}
+	return false
+}
+
+// envValue parses the value `raw` of a variable, which is decoded into the
+// type `rt`.
+func envValue(dec *Decoder, raw string, rt reflect.Type,
+	pos Position) (interface{}, tomlType) {
+
+	if rt = envElem(rt); rt != nil && rt.Kind() == reflect.String {
+		return raw, tomlString
+	}
+	p, err := dec.parse("value = "+raw, pos.File)
+	if err == nil && len(p.mapping) == 1 {
+		if val, ok := p.mapping["value"]; ok {
+			return val, p.types["value"]
+		}
+	}
+	return raw, tomlString
+}
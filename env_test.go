@@ -0,0 +1,145 @@
+package toml
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvLayer(t *testing.T) {
+	env := map[string]string{
+		"APP_TITLE":                   "from env",
+		"APP_TAGS":                    `["a", "b"]`,
+		"APP_DATABASE_CONNECTION_MAX": "100",
+		"APP_DATABASE_PORTS":          "[9001, 9002]",
+		"APP_DATABASE_SERVER":         "10",
+		"APP_SERVERS_GAMMA_IP":        "10.0.0.3",
+		"APP_SERVERS_ALPHA_DC":        "eqdc20",
+		"APP_UNKNOWN_KEY":             "ignored",
+		"OTHER_TITLE":                 "ignored",
+	}
+	for name, val := range env {
+		t.Setenv(name, val)
+	}
+
+	var l Loader
+	l.Add(StringLayer("defaults", loaderDefaults), EnvLayer("APP", "_"))
+
+	var conf loaderConfig
+	md, err := l.Load(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Title != "from env" || conf.Database.ConnectionMax != 100 ||
+		conf.Database.Server != "10" {
+
+		t.Fatalf("Unexpected config: %+v", conf)
+	}
+	if !reflect.DeepEqual(conf.Tags, []string{"a", "b"}) ||
+		!reflect.DeepEqual(conf.Database.Ports, []int{9001, 9002}) {
+
+		t.Fatalf("Expected arrays to be parsed, got %+v", conf)
+	}
+	if conf.Servers["gamma"].IP != "10.0.0.3" ||
+		conf.Servers["alpha"].DC != "eqdc20" ||
+		conf.Servers["alpha"].IP != "10.0.0.1" {
+
+		t.Fatalf("Unexpected servers: %+v", conf.Servers)
+	}
+
+	sources := map[string]string{
+		"database.connection_max": "$APP_DATABASE_CONNECTION_MAX",
+		"servers.alpha.dc":        "$APP_SERVERS_ALPHA_DC",
+		"servers.alpha.ip":        "defaults:11",
+		"database.server":         "$APP_DATABASE_SERVER",
+	}
+	for key, want := range sources {
+		if got := md.Source(strings.Split(key, ".")...); got != want {
+			t.Errorf("Expected source of '%s' to be %q, got %q", key, want, got)
+		}
+	}
+	if md.Type("database", "connection_max") != "Integer" {
+		t.Errorf("Expected overrides to be typed")
+	}
+	if md.IsDefined("unknown") || md.IsDefined("unknown_key") {
+		t.Errorf("Expected variables without a key to be ignored")
+	}
+}
+
+func TestEnvLayerUntyped(t *testing.T) {
+	t.Setenv("CONF__LOG_LEVEL", "debug")
+	t.Setenv("CONF__LIMITS__MAX", "1.5")
+	t.Setenv("CONF__GREETING", `"unterminated`)
+
+	var l Loader
+	l.Add(StringLayer("defaults", "[limits]\nMax = 1.0"),
+		EnvLayer("CONF", "__"))
+
+	var conf map[string]interface{}
+	if _, err := l.Load(&conf); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"log_level": "debug",
+		"greeting":  `"unterminated`,
+		"limits":    map[string]interface{}{"Max": 1.5},
+	}
+	if !reflect.DeepEqual(conf, want) {
+		t.Fatalf("Expected %v but got %v", want, conf)
+	}
+}
+
+func TestEnvLayerConflicts(t *testing.T) {
+	t.Setenv("APP_DATABASE", "oops")
+
+	var l Loader
+	l.Add(StringLayer("defaults", loaderDefaults), EnvLayer("APP", ""))
+
+	var conf loaderConfig
+	_, err := l.Load(&conf)
+	want := "In '$APP_DATABASE', key 'database': Cannot merge a value " +
+		"into a table defined in an earlier layer."
+	if err == nil || err.Error() != want {
+		t.Fatalf("Expected %q but got %v", want, err)
+	}
+}
+
+func TestEnvLayerAliases(t *testing.T) {
+	t.Setenv("APP_SERVER", "b")
+
+	for _, defaults := range []string{"", "host = \"a\"\n"} {
+		var l Loader
+		l.Add(StringLayer("defaults", defaults), EnvLayer("APP", "_"))
+		var conf aliasConfig
+		md, err := l.Load(&conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conf.Host != "b" {
+			t.Errorf("Expected the alias to set the host, got %+v", conf)
+		}
+		if len(defaults) > 0 && md.Source("host") != "$APP_SERVER" {
+			t.Errorf("Expected the alias to override the key 'host', "+
+				"got source %q", md.Source("host"))
+		}
+	}
+}
+
+func TestEnvLayerNoIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"secret.toml": "x = 1\n"})
+	fpath := filepath.Join(dir, "secret.toml")
+	t.Setenv("APP_PATH", `"`+fpath+`"`)
+	t.Setenv("APP_HOME", `"${HOME}"`)
+
+	l := Loader{Decoder: &Decoder{IncludeKey: "value", ExpandEnv: true}}
+	l.Add(EnvLayer("APP", "_"))
+	var v map[string]interface{}
+	if _, err := l.Load(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v["path"] != fpath || v["home"] != "${HOME}" {
+		t.Fatalf("Expected values to be taken as they are, got %v", v)
+	}
+}
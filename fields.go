@@ -0,0 +1,153 @@
+package toml
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// field is what the decoder, the strict checker and the encoder need to know
+// about a struct field. It is worked out once per struct type, since parsing
+// struct tags for every value decoded adds up quickly.
+type field struct {
+	sft  reflect.StructField
+	opts fieldOpts
+
+	// the key name, case folded
+	folded string
+
+	// the rules of the field's `validate` tag, or the error parsing them
+	rules    []rule
+	rulesErr error
+}
+
+type fieldsKey struct {
+	rt      reflect.Type
+	useJSON bool
+}
+
+// fieldCache maps a fieldsKey to the []field of its struct type.
+var fieldCache sync.Map
+
+// cachedFields returns the fields of the struct type `rt`, in order, with
+// their Go names as the key names of untagged fields.
+func cachedFields(rt reflect.Type, useJSON bool) []field {
+	k := fieldsKey{rt, useJSON}
+	if fields, ok := fieldCache.Load(k); ok {
+		return fields.([]field)
+	}
+	fields := make([]field, rt.NumField())
+	for i := range fields {
+		sft := rt.Field(i)
+		f := field{sft: sft, opts: fieldOptions(sft, nil, useJSON)}
+		f.folded = foldKey(f.opts.name)
+		if tag, ok := sft.Tag.Lookup("validate"); ok {
+			f.rules, f.rulesErr = parseRules(tag)
+		}
+		fields[i] = f
+	}
+	actual, _ := fieldCache.LoadOrStore(k, fields)
+	return actual.([]field)
+}
+
+// structFields returns the fields of the struct type `rt`, with the key
+// names of untagged fields passed through `mapper` if it isn't nil.
+func structFields(rt reflect.Type, mapper KeyMapper, useJSON bool) []field {
+	fields := cachedFields(rt, useJSON)
+	if mapper == nil {
+		return fields
+	}
+	mapped := make([]field, len(fields))
+	copy(mapped, fields)
+	for i := range mapped {
+		if !mapped[i].opts.skip && !mapped[i].opts.named {
+			mapped[i].opts.name = mapper(mapped[i].sft.Name)
+			mapped[i].folded = foldKey(mapped[i].opts.name)
+		}
+	}
+	return mapped
+}
+
+// keyIndex finds the keys of a table that match struct fields. The keys are
+// indexed by their case folded forms the first time a name doesn't match
+// exactly, so that matching all the fields of a struct takes time linear in
+// the number of keys rather than in the number of keys times fields.
+type keyIndex struct {
+	tmap   map[string]interface{}
+	fold   bool
+	folded map[string][]string
+}
+
+func newKeyIndex(tmap map[string]interface{}, fold bool) *keyIndex {
+	return &keyIndex{tmap: tmap, fold: fold}
+}
+
+// match finds the key that corresponds to the struct field named `kname`.
+// An exact match always takes precedence. Failing that, and only if folding
+// is on, a single key matching case insensitively is used. If several keys
+// match case insensitively, the match is ambiguous and an error is returned
+// rather than picking one of them at random. `folded` is foldKey(kname), if
+// the caller has it at hand.
+func (ix *keyIndex) match(kname, folded string) (string, bool, error) {
+	if _, ok := ix.tmap[kname]; ok {
+		return kname, true, nil
+	}
+	if !ix.fold {
+		return "", false, nil
+	}
+	if ix.folded == nil {
+		ix.folded = make(map[string][]string, len(ix.tmap))
+		for k := range ix.tmap {
+			f := foldKey(k)
+			ix.folded[f] = append(ix.folded[f], k)
+		}
+	}
+	if len(folded) == 0 {
+		folded = foldKey(kname)
+	}
+	found := ix.folded[folded]
+	switch len(found) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return found[0], true, nil
+	}
+	found = append([]string(nil), found...)
+	sort.Strings(found)
+	return "", false, e("Keys '%s' all match '%s' case insensitively.",
+		strings.Join(found, "', '"), kname)
+}
+
+// foldKey returns the case folded form of `s`: two strings have the same
+// one exactly when strings.EqualFold reports them equal. Every rune is
+// replaced by the smallest rune it folds to.
+func foldKey(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		// The smallest rune an ASCII letter folds to is its upper case
+		// form, since the Kelvin and long s signs are above ASCII.
+		return strings.ToUpper(s)
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		b.WriteRune(min)
+	}
+	return b.String()
+}
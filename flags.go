@@ -0,0 +1,339 @@
+package toml
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type flagLayer struct {
+	dec      *Decoder
+	fs       *flag.FlagSet
+	bindings []*flagBinding
+}
+
+// flagBinding ties a flag to the struct field it sets.
+type flagBinding struct {
+	// the name of the flag
+	name string
+
+	// the struct fields leading from the bound value to the field
+	path []flagField
+
+	value *flagValue
+}
+
+// flagField is the field with index `i` of the struct type `rt`.
+type flagField struct {
+	rt reflect.Type
+	i  int
+}
+
+// BindFlags registers a flag in `fs` for every field of the struct pointed
+// to by `v` that holds a string, a boolean, a number, a time.Time or a slice
+// of those. Flags are named after the TOML keys of their fields, as a
+// decoder without options resolves them, so the field for the key
+// `database.server` gets the flag `-database.server`. The name of each flag
+// starts with `prefix`, which is used as is. The current values of the
+// fields are shown as the defaults of the flags.
+//
+// Slice flags take comma separated values, and time.Time flags take RFC
+// 3339 datetimes.
+//
+// The layer returned holds the values of the flags set on the command line.
+// Add it to a Loader after the other layers, and after `fs` is parsed, so
+// that flags override values from files:
+//
+//	fs := flag.NewFlagSet("app", flag.ExitOnError)
+//	flags := toml.BindFlags(fs, &conf, "")
+//	fs.Parse(os.Args[1:])
+//	var l toml.Loader
+//	l.Add(toml.FileLayer("app.toml"), flags)
+//	md, err := l.Load(&conf)
+//
+// The source of each key set by a flag, as reported by (MetaData).Source, is
+// the name of the flag prefixed with '-'.
+//
+// Fields whose struct type contains itself, such as the next node of a
+// linked list, are bound only as far as the first repetition of the type.
+//
+// BindFlags panics if `v` isn't a pointer to a struct, or if a flag is
+// already defined, as (*flag.FlagSet).Var does.
+func BindFlags(fs *flag.FlagSet, v interface{}, prefix string) Layer {
+	return new(Decoder).BindFlags(fs, v, prefix)
+}
+
+// BindFlags is like the function BindFlags, except that flags are named
+// after the TOML keys of their fields as the decoder resolves them, with its
+// KeyMapper and UseJSONTags. Use the decoder for the Loader too.
+func (dec *Decoder) BindFlags(
+	fs *flag.FlagSet, v interface{}, prefix string) Layer {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic(e("BindFlags needs a pointer to a struct, not %T.", v))
+	}
+
+	l := &flagLayer{dec: dec, fs: fs}
+	l.bind(rv.Elem(), rv.Elem().Type(), prefix, nil,
+		make(map[reflect.Type]bool))
+	return l
+}
+
+// fields returns the fields of the struct type `rt`, named as the decoder
+// of the layer names them.
+func (l *flagLayer) fields(rt reflect.Type) []field {
+	return structFields(rt, l.dec.KeyMapper, l.dec.UseJSONTags)
+}
+
+// bind registers flags for the fields of the struct `rv`, of type `rt`. The
+// value `rv` is invalid if the struct is behind a nil pointer. `onPath`
+// holds the struct types of `path`, which aren't bound again.
+func (l *flagLayer) bind(rv reflect.Value, rt reflect.Type, prefix string,
+	path []flagField, onPath map[reflect.Type]bool) {
+
+	onPath[rt] = true
+	defer delete(onPath, rt)
+
+	for i, f := range l.fields(rt) {
+		sf := f.sft
+		if len(sf.PkgPath) > 0 || f.opts.skip {
+			continue
+		}
+		fieldPath := append(path[:len(path):len(path)], flagField{rt, i})
+
+		var fv reflect.Value
+		if rv.IsValid() {
+			fv = rv.Field(i)
+		}
+		fv, ft := flagElem(fv, sf.Type)
+		switch {
+		case ft == nil || onPath[ft]:
+		case ft.Kind() == reflect.Struct && ft != timeType:
+			l.bind(fv, ft, prefix+f.opts.name+".", fieldPath, onPath)
+		case flagKind(ft) || (ft.Kind() == reflect.Slice &&
+			flagKind(ft.Elem())):
+
+			b := &flagBinding{
+				name:  prefix + f.opts.name,
+				path:  fieldPath,
+				value: &flagValue{rt: ft, def: flagDefault(fv)},
+			}
+			l.fs.Var(b.value, b.name, fmt.Sprintf(
+				"Sets the configuration key '%s'.", l.key(fieldPath)))
+			l.bindings = append(l.bindings, b)
+		}
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// key returns the key of the struct field at the end of `path`, as the
+// decoder of the layer resolves it.
+func (l *flagLayer) key(path []flagField) Key {
+	k := make(Key, len(path))
+	for i, ff := range path {
+		k[i] = l.fields(ff.rt)[ff.i].opts.name
+	}
+	return k
+}
+
+// flagElem dereferences pointers and Optional values of type `rt`. The value
+// returned is invalid if there is no value to dereference, and the type is
+// nil for Primitive values.
+func flagElem(
+	rv reflect.Value, rt reflect.Type) (reflect.Value, reflect.Type) {
+
+	for rt != nil {
+		if rt == primitiveType {
+			return reflect.Value{}, nil
+		}
+		if elem := optionalElem(rt); elem != nil {
+			if rv.IsValid() && rv.CanAddr() {
+				opt := rv.Addr().Interface().(optional)
+				if val, ok := opt.getValue(); ok {
+					rv = val
+				} else {
+					rv = reflect.Value{}
+				}
+			}
+			rt = elem
+		} else if rt.Kind() == reflect.Ptr {
+			if rv.IsValid() && !rv.IsNil() {
+				rv = rv.Elem()
+			} else {
+				rv = reflect.Value{}
+			}
+			rt = rt.Elem()
+		} else {
+			break
+		}
+	}
+	return rv, rt
+}
+
+// flagKind returns true if a flag can set a value of type `rt` directly.
+func flagKind(rt reflect.Type) bool {
+	if rt == timeType {
+		return true
+	}
+	switch rt.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+
+		return true
+	}
+	return false
+}
+
+// flagDefault formats the value `rv` of a field as the default of its flag.
+func flagDefault(rv reflect.Value) string {
+	if !rv.IsValid() {
+		return ""
+	}
+	if rv.Kind() == reflect.Slice {
+		vals := make([]string, rv.Len())
+		for i := range vals {
+			vals[i] = flagDefault(rv.Index(i))
+		}
+		return strings.Join(vals, ",")
+	}
+	if t, ok := rv.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(rv.Interface())
+}
+
+// flagValue is the flag.Value of a bound field. It parses the flag into a
+// TOML value as soon as the flag is set, so that bad values are reported by
+// (*flag.FlagSet).Parse.
+type flagValue struct {
+	rt  reflect.Type
+	def string
+
+	raw string
+	val interface{}
+	typ tomlType
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	if f.val != nil {
+		return f.raw
+	}
+	return f.def
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.rt.Kind() == reflect.Bool
+}
+
+func (f *flagValue) Set(s string) error {
+	if f.rt.Kind() != reflect.Slice {
+		val, typ, err := flagParse(s, f.rt)
+		if err != nil {
+			return err
+		}
+		f.raw, f.val, f.typ = s, val, typ
+		return nil
+	}
+
+	var vals []interface{}
+	if len(s) > 0 {
+		for _, part := range strings.Split(s, ",") {
+			val, _, err := flagParse(strings.TrimSpace(part), f.rt.Elem())
+			if err != nil {
+				return err
+			}
+			vals = append(vals, val)
+		}
+	}
+	f.raw, f.val, f.typ = s, vals, tomlArray
+	if vals == nil {
+		f.val = make([]interface{}, 0)
+	}
+	return nil
+}
+
+// flagParse parses the flag value `s` into a TOML value for a Go value of
+// type `rt`.
+func flagParse(s string, rt reflect.Type) (interface{}, tomlType, error) {
+	if rt == timeType {
+		t, err := time.Parse(time.RFC3339, s)
+		return t, tomlDatetime, err
+	}
+	switch rt.Kind() {
+	case reflect.String:
+		return s, tomlString, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		return b, tomlBool, err
+	case reflect.Float32, reflect.Float64:
+		num, err := strconv.ParseFloat(s, 64)
+		return num, tomlFloat, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+
+		num, err := strconv.ParseUint(s, 10, rt.Bits())
+		if err == nil && num > math.MaxInt64 {
+			err = e("Value %s is out of range for a TOML integer.", s)
+		}
+		return int64(num), tomlInteger, err
+	}
+	num, err := strconv.ParseInt(s, 10, rt.Bits())
+	return num, tomlInteger, err
+}
+
+func (l *flagLayer) parse(
+	dec *Decoder, merged *parser, rt reflect.Type) (*parser, error) {
+
+	set := make(map[string]bool)
+	l.fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var p *parser
+	for _, b := range l.bindings {
+		if !set[b.name] {
+			continue
+		}
+		if p == nil {
+			p = newLayerParser()
+		}
+		err := setKey(p, b.key(dec, merged.mapping), b.value.val,
+			b.value.typ, Position{File: "-" + b.name})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// key returns the key of the bound field in the data `table`, resolving
+// each field the way the decoder `dec` does, so that keys defined by earlier
+// layers are overridden rather than duplicated.
+func (b *flagBinding) key(dec *Decoder, table map[string]interface{}) Key {
+	key := make(Key, 0, len(b.path))
+	for _, ff := range b.path {
+		f := structFields(ff.rt, dec.KeyMapper, dec.UseJSONTags)[ff.i]
+		name := f.opts.name
+		ix := newKeyIndex(table, !dec.NoCaseFolding)
+		if k, ok, _, _ := matchField(ix, f); ok {
+			name = k
+		}
+		key = append(key, name)
+		table, _ = table[name].(map[string]interface{})
+	}
+	return key
+}
@@ -0,0 +1,205 @@
+package toml
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type flagConfig struct {
+	Title    string
+	Debug    bool
+	Ratio    float64
+	Tags     []string
+	Ignored  string `toml:"-"`
+	Database struct {
+		Server        string
+		Ports         []int
+		ConnectionMax int `toml:"connection_max"`
+	} `toml:"database"`
+	Owner *struct {
+		Name string
+	}
+	Limits map[string]int
+}
+
+func TestBindFlags(t *testing.T) {
+	conf := flagConfig{Title: "default", Ratio: 0.5}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(new(bytes.Buffer))
+	flags := BindFlags(fs, &conf, "")
+
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name+"="+f.DefValue)
+	})
+	want := []string{"Debug=false", "Owner.Name=", "Ratio=0.5", "Tags=",
+		"Title=default", "database.Ports=", "database.Server=",
+		"database.connection_max=0"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected flags %v but got %v", want, names)
+	}
+
+	err := fs.Parse([]string{"-debug", "-database.connection_max", "500",
+		"-database.Ports=1,2", "-Owner.Name", "Tom"})
+	if err == nil {
+		t.Fatal("Expected flag names to be case sensitive")
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags = BindFlags(fs, &conf, "")
+	err = fs.Parse([]string{"-Debug", "-database.connection_max", "500",
+		"-database.Ports=1,2", "-Owner.Name", "Tom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var l Loader
+	l.Add(StringLayer("defaults", loaderDefaults), flags)
+	md, err := l.Load(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Title != "defaults" || !conf.Debug ||
+		conf.Database.ConnectionMax != 500 ||
+		conf.Database.Server != "localhost" ||
+		!reflect.DeepEqual(conf.Database.Ports, []int{1, 2}) ||
+		conf.Owner == nil || conf.Owner.Name != "Tom" {
+
+		t.Fatalf("Unexpected config: %+v", conf)
+	}
+
+	sources := map[string]string{
+		"database.connection_max": "-database.connection_max",
+		"database.ports":          "-database.Ports",
+		"database.server":         "defaults:6",
+		"Debug":                   "-Debug",
+	}
+	for key, want := range sources {
+		if got := md.Source(strings.Split(key, ".")...); got != want {
+			t.Errorf("Expected source of '%s' to be %q, got %q", key, want, got)
+		}
+	}
+	if md.IsDefined("database", "Ports") {
+		t.Errorf("Expected flags to override keys of earlier layers")
+	}
+}
+
+func TestBindFlagsPrefix(t *testing.T) {
+	var conf flagConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(new(bytes.Buffer))
+	flags := BindFlags(fs, &conf, "app.")
+
+	if err := fs.Parse([]string{"-app.Ratio", "many"}); err == nil {
+		t.Fatal("Expected a bad flag value to be reported")
+	}
+	if err := fs.Parse([]string{"-app.Ratio", "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var l Loader
+	l.Add(flags)
+	if _, err := l.Load(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Ratio != 2 {
+		t.Fatalf("Expected the ratio to be set, got %v", conf.Ratio)
+	}
+}
+
+func TestBindFlagsRange(t *testing.T) {
+	var conf struct {
+		Port  int16
+		UPort uint16
+	}
+	tests := []struct {
+		arg string
+		ok  bool
+	}{
+		{"-Port=32767", true},
+		{"-Port=-32768", true},
+		{"-Port=70000", false},
+		{"-UPort=65535", true},
+		{"-UPort=70000", false},
+		{"-UPort=-1", false},
+	}
+	for _, test := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(new(bytes.Buffer))
+		BindFlags(fs, &conf, "")
+		err := fs.Parse([]string{test.arg})
+		if test.ok && err != nil {
+			t.Errorf("%s: %s", test.arg, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("%s: Expected the value to be out of range", test.arg)
+		}
+	}
+}
+
+func TestBindFlagsAlias(t *testing.T) {
+	var conf aliasConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(new(bytes.Buffer))
+	flags := BindFlags(fs, &conf, "")
+	if err := fs.Parse([]string{"-host", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var l Loader
+	l.Add(StringLayer("defaults", "server = \"a\"\n"), flags)
+	md, err := l.Load(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Host != "b" || md.Source("server") != "-host" ||
+		md.IsDefined("host") {
+
+		t.Fatalf("Expected the flag to override the alias, got %+v", conf)
+	}
+}
+
+type flagNode struct {
+	Name string
+	Next *flagNode
+}
+
+func TestBindFlagsCycle(t *testing.T) {
+	var node flagNode
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	BindFlags(fs, &node, "")
+
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	if want := []string{"Name"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected flags %v but got %v", want, names)
+	}
+}
+
+func TestBindFlagsKeyMapper(t *testing.T) {
+	var conf flagConfig
+	dec := &Decoder{KeyMapper: SnakeCase}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(new(bytes.Buffer))
+	flags := dec.BindFlags(fs, &conf, "")
+	err := fs.Parse([]string{"-database.connection_max", "5", "-title", "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := Loader{Decoder: dec}
+	l.Add(StringLayer("defaults", "title = \"d\"\n"), flags)
+	md, err := l.Load(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Title != "t" || conf.Database.ConnectionMax != 5 ||
+		md.Source("title") != "-title" {
+
+		t.Fatalf("Unexpected config: %+v", conf)
+	}
+}
@@ -0,0 +1,203 @@
+package toml
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// GeneratedUnmarshaler is implemented by the code that the tomlgen command
+// generates. The decoder calls DecodeTOML rather than UnmarshalTOML, so that
+// the generated code reports through a *Report which keys it decodes, and
+// (MetaData).Undecoded and (MetaData).Warnings are the same as if the value
+// was decoded with reflection.
+//
+// The generated code doesn't implement every option of a Decoder. With
+// CoerceNumbers, BigNumbers or Hooks set, or with a KeyMapper or UseJSONTags
+// other than those the code was generated for, the decoder ignores it and
+// uses reflection.
+type GeneratedUnmarshaler interface {
+	Unmarshaler
+
+	// TOMLNaming returns the -keys and -json flags of tomlgen that the code
+	// was generated with.
+	TOMLNaming() (keys string, useJSON bool)
+
+	DecodeTOML(r *Report, data interface{}) error
+}
+
+// keyMapperNames are the key mappers that the -keys flag of tomlgen names.
+var keyMapperNames = map[string]KeyMapper{
+	"snake":      SnakeCase,
+	"kebab":      KebabCase,
+	"lowercamel": LowerCamelCase,
+}
+
+// Report is how the code that tomlgen generates tells the decoder what it
+// decodes. A nil *Report, which UnmarshalTOML uses, matches keys with the
+// default options and records nothing.
+type Report struct {
+	md      *MetaData
+	context Key
+
+	// the table being decoded into the struct that `v` points to, if any,
+	// and the keys of it that were used
+	tmap map[string]interface{}
+	v    interface{}
+	ix   *keyIndex
+	used map[string]bool
+}
+
+// Struct starts decoding `data` into the struct that `v` points to. It
+// returns the report for the struct's fields, and `data` as a table.
+func (r *Report) Struct(data, v interface{}) (*Report,
+	map[string]interface{}, error) {
+
+	tmap, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, nil, e("Type mismatch for %s. Expected map but found "+
+			"'%T'.", reflect.TypeOf(v).Elem(), data)
+	}
+	s := &Report{tmap: tmap, v: v, used: make(map[string]bool, len(tmap))}
+	s.ix = newKeyIndex(tmap, true)
+	if r != nil && r.md != nil {
+		s.md, s.context = r.md, r.context
+		s.ix.fold = !r.md.dec.NoCaseFolding
+	}
+	return s, tmap, nil
+}
+
+// Field finds the key in the table of a struct that sets its field named
+// `field`, whose key name is `name`, as the decoder does.
+func (r *Report) Field(field, name string) (string, bool, error) {
+	key, ok, err := r.ix.match(name, "")
+	if err != nil {
+		return "", false, &DecodeError{Err: e("Cannot decode '%s.%s': %s",
+			r.typeName(), field, err)}
+	}
+	if !ok {
+		return "", false, nil
+	}
+	r.used[key] = true
+	if r.md != nil {
+		r.md.decoded[r.context.add(key).String()] = true
+		if key != name {
+			r.md.warn(CaseFoldedKey, r.context.add(key), "Key '%s' "+
+				"matches the field '%s.%s' only case insensitively.",
+				key, r.typeName(), field)
+		}
+	}
+	return key, true, nil
+}
+
+// FieldError returns `err`, which occurred decoding the struct field named
+// `field` from the key `key`, as a *DecodeError.
+func (r *Report) FieldError(key, field string, err error) error {
+	de, ok := err.(*DecodeError)
+	if !ok {
+		de = &DecodeError{Err: e("Type mismatch for '%s.%s': %s",
+			r.typeName(), field, err)}
+	}
+	return &DecodeError{Key: append(Key{key}, de.Key...), Err: de.Err}
+}
+
+// Done finishes decoding the table of a struct, warning about its keys that
+// no field used.
+func (r *Report) Done() {
+	if r.md == nil {
+		return
+	}
+	for _, k := range sortedKeys(r.tmap) {
+		if !r.used[k] {
+			r.md.warn(UnknownKey, r.context.add(k),
+				"Key '%s' doesn't match any field of '%s'.", k, r.typeName())
+		}
+	}
+}
+
+// At returns the report for the value of `key`, such as the key of a field.
+func (r *Report) At(key string) *Report {
+	if r == nil || r.md == nil {
+		return nil
+	}
+	return &Report{md: r.md, context: r.context.add(key)}
+}
+
+// Entry returns the report for the value of `key` in a table decoded into a
+// map, which is decoded.
+func (r *Report) Entry(key string) *Report {
+	if r == nil || r.md == nil {
+		return nil
+	}
+	r.md.decoded[r.context.add(key).String()] = true
+	return r.At(key)
+}
+
+// Decoded records that all of `data` was decoded, as it is when it goes
+// into an empty interface.
+func (r *Report) Decoded(data interface{}) {
+	if r == nil || r.md == nil {
+		return
+	}
+	if tmap, ok := data.(map[string]interface{}); ok {
+		r.md.decodedAll(tmap, r.context)
+	}
+}
+
+// Unmarshal decodes `data` with the UnmarshalTOML method of `u`.
+func (r *Report) Unmarshal(u Unmarshaler, data interface{}) error {
+	if err := u.UnmarshalTOML(data); err != nil {
+		if _, ok := err.(*DecodeError); !ok {
+			err = &DecodeError{Err: err}
+		}
+		return err
+	}
+	r.Decoded(data)
+	return nil
+}
+
+// Float32 returns `f` as a float32, warning if it is rounded.
+func (r *Report) Float32(f float64) float32 {
+	f32 := float32(f)
+	if r != nil && r.md != nil && float64(f32) != f && !math.IsNaN(f) {
+		r.md.warn(LossyConversion, r.context, "Float %s is rounded to %s "+
+			"to fit float32.", strconv.FormatFloat(f, 'g', -1, 64),
+			strconv.FormatFloat(float64(f32), 'g', -1, 64))
+	}
+	return f32
+}
+
+func (r *Report) typeName() string {
+	return reflect.TypeOf(r.v).Elem().String()
+}
+
+// useGenerated returns true if the generated code of `u` decodes like
+// reflection does with the options of the decoder.
+func (md *MetaData) useGenerated(u GeneratedUnmarshaler) bool {
+	dec := md.dec
+	if dec.CoerceNumbers || dec.BigNumbers || len(dec.Hooks) > 0 {
+		return false
+	}
+	keys, useJSON := u.TOMLNaming()
+	mapper := keyMapperNames[keys]
+	switch {
+	case useJSON != dec.UseJSONTags:
+		return false
+	case mapper == nil || dec.KeyMapper == nil:
+		return mapper == nil && dec.KeyMapper == nil
+	}
+	return reflect.ValueOf(mapper).Pointer() ==
+		reflect.ValueOf(dec.KeyMapper).Pointer()
+}
+
+func (md *MetaData) unifyGenerated(
+	data interface{}, u GeneratedUnmarshaler) error {
+
+	context := make(Key, len(md.context))
+	copy(context, md.context)
+	r := &Report{md: md, context: context}
+	if err := u.DecodeTOML(r, data); err != nil {
+		return md.unmarshalError(err)
+	}
+	return nil
+}
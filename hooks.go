@@ -0,0 +1,152 @@
+package toml
+
+import (
+	"reflect"
+	"time"
+)
+
+// DecodeHook decodes TOML values into Go values of a type that the decoder
+// doesn't know how to decode by itself, such as *regexp.Regexp or
+// os.FileMode, or decodes them differently than it would. Hooks are set on a
+// Decoder, and run before the decoder's own rules for every value they apply
+// to, including values decoded by (MetaData).PrimitiveDecode and values
+// checked by strict decoding.
+//
+// Several hooks may apply to the same value. They run in order, until one
+// returns a value of the type `To`; the values other hooks return are passed
+// on to the next hook, and finally to the decoder's own rules. So a hook may
+// also rewrite TOML data for the hooks after it, e.g., to expand `~` in
+// strings before they are decoded into paths.
+type DecodeHook struct {
+	// From is the TOML type of the values the hook applies to, as reported
+	// by (MetaData).Type, such as "String" or "Hash". An empty From applies
+	// to values of every type.
+	From string
+
+	// To is the Go type the hook decodes into. If it is a pointer type, the
+	// hook also applies to values of the type it points to.
+	To reflect.Type
+
+	// Func decodes a TOML value, which is a string, int64, float64, bool,
+	// time.Time, []interface{} or map[string]interface{}, or a *big.Int or
+	// *big.Float if the Decoder has the BigNumbers option set. Errors it
+	// returns are reported as a *DecodeError at the key of the value.
+	Func func(data interface{}) (interface{}, error)
+}
+
+// HookFunc returns a DecodeHook that runs the function `fn`, which must have
+// the signature
+//
+//	func(data T) (U, error)
+//
+// The hook decodes TOML values of the Go type T, one of the types described
+// by DecodeHook.Func or interface{} for any value, into Go values of type U.
+// For example:
+//
+//	toml.HookFunc(func(s string) (*regexp.Regexp, error) {
+//		return regexp.Compile(s)
+//	})
+//
+// HookFunc panics if `fn` is not such a function.
+func HookFunc(fn interface{}) DecodeHook {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 1 || ft.NumOut() != 2 ||
+		ft.Out(1) != errorType {
+
+		panic(e("HookFunc needs a function of the form "+
+			"func(T) (U, error), not %T.", fn))
+	}
+	from, ok := hookTypes[ft.In(0)]
+	if !ok {
+		panic(e("HookFunc cannot decode TOML values as %s.", ft.In(0)))
+	}
+
+	in := ft.In(0)
+	return DecodeHook{
+		From: from,
+		To:   ft.Out(0),
+		Func: func(data interface{}) (interface{}, error) {
+			arg := reflect.New(in).Elem()
+			arg.Set(reflect.ValueOf(data))
+			out := fv.Call([]reflect.Value{arg})
+			err, _ := out[1].Interface().(error)
+			return out[0].Interface(), err
+		},
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// hookTypes maps the Go types of TOML values to the names of their types.
+var hookTypes = map[reflect.Type]string{
+	reflect.TypeOf(""):                         tomlString.typeString(),
+	reflect.TypeOf(int64(0)):                   tomlInteger.typeString(),
+	reflect.TypeOf(float64(0)):                 tomlFloat.typeString(),
+	reflect.TypeOf(false):                      tomlBool.typeString(),
+	reflect.TypeOf(time.Time{}):                tomlDatetime.typeString(),
+	reflect.TypeOf([]interface{}(nil)):         tomlArray.typeString(),
+	reflect.TypeOf(map[string]interface{}{}):   tomlHash.typeString(),
+	reflect.TypeOf((*interface{})(nil)).Elem(): "",
+}
+
+// applies returns true if the hook decodes `data` into values of type `rt`.
+func (h DecodeHook) applies(data interface{}, rt reflect.Type) bool {
+	if h.To != rt && (h.To.Kind() != reflect.Ptr || h.To.Elem() != rt) {
+		return false
+	}
+	return len(h.From) == 0 || h.From == typeOfValue(data).typeString()
+}
+
+// runHooks runs the hooks in `hooks` that apply to decoding `data` into a
+// value of type `rt`. It returns the value decoded by a hook, which is
+// invalid if none decoded one, and the data to decode otherwise.
+func runHooks(hooks []DecodeHook, data interface{},
+	rt reflect.Type) (reflect.Value, interface{}, error) {
+
+	for _, h := range hooks {
+		if !h.applies(data, rt) {
+			continue
+		}
+		v, err := h.Func(data)
+		if err != nil {
+			return reflect.Value{}, nil, err
+		}
+
+		rv := reflect.ValueOf(v)
+		switch {
+		case v == nil:
+			return reflect.Zero(rt), nil, nil
+		case rv.Type().AssignableTo(rt):
+			return rv, nil, nil
+		case rv.Type() == h.To:
+			// The hook decodes into a pointer to `rt`.
+			if rv.IsNil() {
+				return reflect.Zero(rt), nil, nil
+			}
+			return rv.Elem(), nil, nil
+		}
+		data = v
+	}
+	return reflect.Value{}, data, nil
+}
+
+// unifyHooks decodes `data` into `rv` with the hooks of the decoder. It
+// returns true if a hook decoded the value, and otherwise the data to decode
+// instead of `data`.
+func (md *MetaData) unifyHooks(
+	data interface{}, rv reflect.Value) (interface{}, bool, error) {
+
+	v, rest, err := runHooks(md.dec.Hooks, data, rv.Type())
+	if err != nil {
+		return nil, true, md.errorAt(md.context, err)
+	}
+	if v.IsValid() {
+		rv.Set(v)
+		if tmap, ok := data.(map[string]interface{}); ok {
+			md.decodedAll(tmap, md.context)
+		}
+		return nil, true, nil
+	}
+	return rest, false, nil
+}
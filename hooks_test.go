@@ -0,0 +1,189 @@
+package toml
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var hookData = `
+pattern = "^a+b$"
+mode = "0644"
+home = "~/src"
+
+[point]
+x = 1
+y = 2
+`
+
+type hookPoint struct{ X, Y int }
+
+var hooks = []DecodeHook{
+	HookFunc(func(s string) (*regexp.Regexp, error) {
+		return regexp.Compile(s)
+	}),
+	HookFunc(func(s string) (os.FileMode, error) {
+		var mode os.FileMode
+		for _, c := range s {
+			if c < '0' || c > '7' {
+				return 0, e("'%s' is not an octal file mode.", s)
+			}
+			mode = mode*8 + os.FileMode(c-'0')
+		}
+		return mode, nil
+	}),
+	{
+		From: "Hash",
+		To:   reflect.TypeOf(hookPoint{}),
+		Func: func(data interface{}) (interface{}, error) {
+			m := data.(map[string]interface{})
+			return hookPoint{int(m["y"].(int64)), int(m["x"].(int64))}, nil
+		},
+	},
+}
+
+// decodeHooks decodes `data` into `v` with the hooks given.
+func decodeHooks(data string, v interface{}, hooks ...DecodeHook) (MetaData,
+	error) {
+
+	dec := NewDecoder(strings.NewReader(data))
+	dec.Hooks = hooks
+	return dec.Decode(v)
+}
+
+func TestHooks(t *testing.T) {
+	var v struct {
+		Pattern *regexp.Regexp
+		Mode    os.FileMode
+		Point   hookPoint
+		Home    string
+	}
+	md, err := decodeHooks(hookData, &v, hooks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Pattern == nil || !v.Pattern.MatchString("aab") {
+		t.Errorf("Expected a compiled pattern but got %v", v.Pattern)
+	}
+	if v.Mode != 0644 {
+		t.Errorf("Expected mode 0644 but got %o", v.Mode)
+	}
+	if v.Point != (hookPoint{2, 1}) {
+		t.Errorf("Expected the point to be decoded by its hook but got %v",
+			v.Point)
+	}
+	if v.Home != "~/src" {
+		t.Errorf("Expected strings without hooks to decode as usual, "+
+			"but got %q", v.Home)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		t.Errorf("Expected tables decoded by hooks to be decoded, "+
+			"but %v weren't", undecoded)
+	}
+}
+
+func TestHooksCompose(t *testing.T) {
+	home := HookFunc(func(s string) (string, error) {
+		return strings.Replace(s, "~", "/home/me", 1), nil
+	})
+	var v struct{ Home string }
+	if _, err := decodeHooks(`home = "~/src"`, &v, home); err != nil {
+		t.Fatal(err)
+	}
+	if v.Home != "/home/me/src" {
+		t.Errorf("Expected '/home/me/src' but got %q", v.Home)
+	}
+
+	// A hook that returns a value of another type passes it on.
+	expand := DecodeHook{
+		From: "String",
+		To:   reflect.TypeOf(&regexp.Regexp{}),
+		Func: func(data interface{}) (interface{}, error) {
+			return "^" + data.(string) + "$", nil
+		},
+	}
+	var r struct{ Pattern *regexp.Regexp }
+	_, err := decodeHooks(`pattern = "a+"`, &r, expand, hooks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pattern.String() != "^a+$" {
+		t.Errorf("Expected the rewritten pattern '^a+$' but got %q",
+			r.Pattern)
+	}
+}
+
+func TestHooksError(t *testing.T) {
+	var v struct {
+		Server struct{ Mode os.FileMode }
+	}
+	_, err := decodeHooks("[server]\nmode = \"0x44\"", &v, hooks...)
+	derr, ok := err.(*DecodeError)
+	if !ok {
+		t.Fatalf("Expected a *DecodeError but got %v", err)
+	}
+	if derr.Position.Line != 2 || derr.Key.String() != "server.mode" {
+		t.Errorf("Expected the error at line 2, key 'server.mode', "+
+			"but got line %d, key '%s'", derr.Position.Line, derr.Key)
+	}
+	want := "Near line 2, key 'server.mode': " +
+		"'0x44' is not an octal file mode."
+	if err.Error() != want {
+		t.Errorf("Expected %q but got %q", want, err.Error())
+	}
+}
+
+func TestHooksPrimitiveDecode(t *testing.T) {
+	var v struct{ Pattern Primitive }
+	md, err := decodeHooks(hookData, &v, hooks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r *regexp.Regexp
+	if err := md.PrimitiveDecode(v.Pattern, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.String() != "^a+b$" {
+		t.Errorf("Expected a compiled pattern but got %v", r)
+	}
+}
+
+func TestHooksStrict(t *testing.T) {
+	var v struct {
+		Pattern *regexp.Regexp
+		Mode    os.FileMode
+		Point   hookPoint
+		Home    string
+	}
+	dec := NewDecoder(strings.NewReader(hookData))
+	dec.Hooks = hooks
+	if _, err := dec.DecodeStrict(&v, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dec = NewDecoder(strings.NewReader(`mode = "9"`))
+	dec.Hooks = hooks
+	_, err := dec.DecodeStrict(&v, nil)
+	if err == nil || !strings.Contains(err.Error(), "not an octal") {
+		t.Errorf("Expected the hook's error but got %v", err)
+	}
+}
+
+func TestHookFuncPanics(t *testing.T) {
+	for _, fn := range []interface{}{
+		"not a function",
+		func(s string) string { return s },
+		func(n int) (int, error) { return n, nil },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected HookFunc to panic for %T", fn)
+				}
+			}()
+			HookFunc(fn)
+		}()
+	}
+}
@@ -413,6 +413,8 @@ func lexRawString(lx *lexer) stateFn {
 	switch {
 	case isNL(r):
 		return lx.errorf("Strings cannot contain new lines.")
+	case r == eof:
+		return lx.errorf("Unexpected EOF.")
 	case r == rawStringEnd:
 		lx.backup()
 		lx.emit(itemRawString)
@@ -430,6 +432,8 @@ func lexString(lx *lexer) stateFn {
 	switch {
 	case isNL(r):
 		return lx.errorf("Strings cannot contain new lines.")
+	case r == eof:
+		return lx.errorf("Unexpected EOF.")
 	case r == '\\':
 		return lexStringEscape
 	case r == stringEnd:
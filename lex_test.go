@@ -58,3 +58,17 @@ func TestLexer(t *testing.T) {
 		testf("%s\n", item)
 	}
 }
+
+func TestLexerUnterminatedString(t *testing.T) {
+	for _, data := range []string{`a = "abc`, `a = 'abc`} {
+		lx := lex(data)
+		for {
+			item := lx.nextItem()
+			if item.typ == itemError {
+				break
+			} else if item.typ == itemEOF {
+				t.Fatalf("Expected an error for %q", data)
+			}
+		}
+	}
+}
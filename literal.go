@@ -0,0 +1,181 @@
+package toml
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Literal describes how a value was written in TOML data, which the value
+// itself doesn't tell: `007` and `7` decode to the same integer, and
+// `'C:\dir'` and `"C:\\dir"` to the same string.
+//
+// Decoding a value into a Literal gives its literal instead of the value, and
+// encoding a Literal writes its text as is, so a value can be written back
+// exactly as it was read:
+//
+//	type Config struct {
+//		Mode toml.Literal // e.g. 0644, with the leading zero kept
+//	}
+type Literal struct {
+	// Text is the value as it was written, including quotes. Arrays are
+	// written with their elements separated by ", ", without the whitespace
+	// and comments they had.
+	Text string
+
+	// Style tells what kind of literal Text is.
+	Style LiteralStyle
+
+	// Elements holds the literals of the elements of an array.
+	Elements []Literal
+}
+
+// LiteralStyle is the kind of a literal.
+type LiteralStyle int
+
+const (
+	// BasicString is a string in double quotes, which may have escapes.
+	BasicString LiteralStyle = iota + 1
+
+	// RawString is a string in single quotes, taken as is.
+	RawString
+
+	IntegerLiteral
+	FloatLiteral
+	BoolLiteral
+	DatetimeLiteral
+	ArrayLiteral
+)
+
+func (s LiteralStyle) String() string {
+	switch s {
+	case BasicString:
+		return "basic string"
+	case RawString:
+		return "raw string"
+	case IntegerLiteral:
+		return "integer"
+	case FloatLiteral:
+		return "float"
+	case BoolLiteral:
+		return "bool"
+	case DatetimeLiteral:
+		return "datetime"
+	case ArrayLiteral:
+		return "array"
+	}
+	return "none"
+}
+
+// String returns the text of the literal.
+func (lit Literal) String() string {
+	return lit.Text
+}
+
+var literalType = reflect.TypeOf(Literal{})
+
+// literalOfItem returns the literal of the lexer item `it`, which is a
+// string, number, boolean or datetime.
+func literalOfItem(it item) Literal {
+	switch it.typ {
+	case itemString:
+		return Literal{Text: `"` + it.val + `"`, Style: BasicString}
+	case itemRawString:
+		return Literal{Text: "'" + it.val + "'", Style: RawString}
+	case itemInteger:
+		return Literal{Text: it.val, Style: IntegerLiteral}
+	case itemFloat:
+		return Literal{Text: it.val, Style: FloatLiteral}
+	case itemBool:
+		return Literal{Text: it.val, Style: BoolLiteral}
+	}
+	return Literal{Text: it.val, Style: DatetimeLiteral}
+}
+
+// arrayLiteral returns the literal of an array with the elements `elems`.
+func arrayLiteral(elems []Literal) Literal {
+	texts := make([]string, len(elems))
+	for i, elem := range elems {
+		texts[i] = elem.Text
+	}
+	return Literal{
+		Text:     "[" + strings.Join(texts, ", ") + "]",
+		Style:    ArrayLiteral,
+		Elements: elems,
+	}
+}
+
+// literalOf returns the literal a value would be written as by default. It
+// is used for values that weren't read from TOML data, such as values set by
+// environment variables. Tables have no literal.
+func literalOf(val interface{}) Literal {
+	switch val := val.(type) {
+	case int64:
+		return Literal{Text: strconv.FormatInt(val, 10), Style: IntegerLiteral}
+	case *big.Int:
+		return Literal{Text: val.String(), Style: IntegerLiteral}
+	case float64:
+		return floatLiteral(strconv.FormatFloat(val, 'f', -1, 64))
+	case *big.Float:
+		return floatLiteral(val.Text('f', -1))
+	case string:
+		return Literal{Text: quote(val), Style: BasicString}
+	case bool:
+		return Literal{Text: strconv.FormatBool(val), Style: BoolLiteral}
+	case time.Time:
+		return Literal{Text: val.UTC().Format("2006-01-02T15:04:05Z"),
+			Style: DatetimeLiteral}
+	case []interface{}:
+		elems := make([]Literal, len(val))
+		for i, v := range val {
+			elems[i] = literalOf(v)
+		}
+		return arrayLiteral(elems)
+	}
+	return Literal{}
+}
+
+// floatLiteral returns the literal of a float formatted as `s`, adding the
+// decimal point that TOML floats need if `s` has none.
+func floatLiteral(s string) Literal {
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return Literal{Text: s, Style: FloatLiteral}
+}
+
+// literal returns the literal of the value `val` at the key `key` in the
+// data of a parser.
+func literal(literals map[string]Literal, key Key, val interface{}) Literal {
+	if lit, ok := literals[key.String()]; ok {
+		return lit
+	}
+	return literalOf(val)
+}
+
+// Literal returns how the value of the key given was written in the TOML
+// data. Values that didn't come from TOML data, such as those set by
+// environment variables, get the literal they would be written as by
+// default. The zero Literal is returned for tables and keys that aren't
+// defined. Keys are case sensitive.
+func (md MetaData) Literal(key ...string) Literal {
+	if !md.IsDefined(key...) {
+		return Literal{}
+	}
+	var val interface{} = md.mapping
+	for _, k := range key {
+		val = val.(map[string]interface{})[k]
+	}
+	return literal(md.literals, Key(key), val)
+}
+
+func (md *MetaData) unifyLiteral(data interface{}, rv reflect.Value) error {
+	lit := literal(md.literals, md.context, data)
+	if lit.Style == 0 {
+		return badtype("value", data)
+	}
+	rv.Set(reflect.ValueOf(lit))
+	return nil
+}
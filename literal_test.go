@@ -0,0 +1,137 @@
+package toml
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var literalData = `
+mode = 0644
+ratio = 1.50
+path = 'C:\dir'
+name = "caf\u00e9"
+dob = 1979-05-27T07:32:00Z
+ports = [ 8001, # first
+  08002 ]
+
+[owner]
+enabled = true
+`
+
+func TestMetaDataLiteral(t *testing.T) {
+	var v map[string]interface{}
+	md, err := Decode(literalData, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]Literal{
+		"mode":          {Text: "0644", Style: IntegerLiteral},
+		"ratio":         {Text: "1.50", Style: FloatLiteral},
+		"path":          {Text: `'C:\dir'`, Style: RawString},
+		"name":          {Text: `"caf\u00e9"`, Style: BasicString},
+		"dob":           {Text: "1979-05-27T07:32:00Z", Style: DatetimeLiteral},
+		"owner.enabled": {Text: "true", Style: BoolLiteral},
+		"owner":         {},
+		"missing":       {},
+		"ports": {Text: "[8001, 08002]", Style: ArrayLiteral,
+			Elements: []Literal{
+				{Text: "8001", Style: IntegerLiteral},
+				{Text: "08002", Style: IntegerLiteral},
+			}},
+	}
+	for key, want := range tests {
+		k, _ := ParseKey(key)
+		if got := md.Literal(k...); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Expected %#v but got %#v", key, want, got)
+		}
+	}
+	if s := md.Literal("path").Style.String(); s != "raw string" {
+		t.Errorf("Expected the style 'raw string' but got %q", s)
+	}
+}
+
+func TestLiteralRoundTrip(t *testing.T) {
+	var v struct {
+		Mode  Literal
+		Ratio Literal
+		Path  Literal
+		Name  Literal
+		Ports []Literal
+	}
+	if _, err := Decode(literalData, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Ports[1].Text != "08002" {
+		t.Errorf("Expected the literal of an element, but got %#v",
+			v.Ports[1])
+	}
+
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(struct {
+		Mode, Ratio, Path, Name Literal
+	}{v.Mode, v.Ratio, v.Path, v.Name}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Mode = 0644\nRatio = 1.50\nPath = 'C:\\dir'\n" +
+		"Name = \"caf\\u00e9\"\n"
+	if buf.String() != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, buf.String())
+	}
+
+	var bad struct{ Owner Literal }
+	_, err := Decode(literalData, &bad)
+	want = "Near line 10, key 'owner': Type mismatch for " +
+		"'struct { Owner toml.Literal }.Owner': Expected value but found " +
+		"'map[string]interface {}'."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+}
+
+func TestLiteralDefaults(t *testing.T) {
+	os.Setenv("LITERAL_RATIO", "2")
+	defer os.Unsetenv("LITERAL_RATIO")
+
+	var l Loader
+	l.Arrays = AppendArrays
+	l.Add(StringLayer("defaults", literalData),
+		StringLayer("site", "ports = [ 09 ]"),
+		EnvLayer("LITERAL", ""))
+	var v map[string]interface{}
+	md, err := l.Load(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lit := md.Literal("ports"); lit.Text != "[8001, 08002, 09]" {
+		t.Errorf("Expected appended arrays to keep their literals, but "+
+			"got %q", lit.Text)
+	}
+	if lit := md.Literal("ratio"); lit.Text != "2" {
+		t.Errorf("Expected the literal of a variable, but got %q", lit.Text)
+	}
+	if lit := md.Literal("mode"); lit.Text != "0644" {
+		t.Errorf("Expected the literal of a layer, but got %q", lit.Text)
+	}
+
+	tree, err := LoadTree(literalData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lit := tree.Literal("mode"); lit.Text != "0644" {
+		t.Errorf("Expected the literal '0644' but got %q", lit.Text)
+	}
+	tree.Set("mode", 420)
+	tree.Set("ratio", 2.0)
+	tree.Set("name", "a\tb")
+	for key, want := range map[string]string{
+		"mode": "420", "ratio": "2.0", "name": `"a\tb"`,
+	} {
+		if lit := tree.Literal(key); lit.Text != want {
+			t.Errorf("%s: Expected the literal %q but got %q", key, want,
+				lit.Text)
+		}
+	}
+}
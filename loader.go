@@ -0,0 +1,343 @@
+package toml
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+)
+
+// Loader decodes several layers of TOML data, such as defaults, a site file
+// and a host file, into a single Go value. The layers are parsed and merged
+// into one mapping, in increasing order of precedence, before the mapping
+// is decoded. The MetaData returned knows which layer supplied each value;
+// see (MetaData).Source.
+//
+// The zero value is ready to use. It merges tables key by key and lets
+// arrays of later layers replace those of earlier ones.
+type Loader struct {
+	// Decoder, when set, supplies the options used to parse each layer and
+	// to decode the merged result.
+	Decoder *Decoder
+
+	// Tables controls how a table combines with a table of the same key
+	// from an earlier layer.
+	Tables TableMerge
+
+	// Arrays controls how an array combines with an array of the same key
+	// from an earlier layer.
+	Arrays ArrayMerge
+
+	layers []Layer
+}
+
+// TableMerge is a rule for combining tables of the same key.
+type TableMerge int
+
+const (
+	// MergeTables merges the keys of both tables, recursively. Values in
+	// the later table take precedence.
+	MergeTables TableMerge = iota
+
+	// ReplaceTables replaces the earlier table as a whole.
+	ReplaceTables
+)
+
+// ArrayMerge is a rule for combining arrays of the same key.
+type ArrayMerge int
+
+const (
+	// ReplaceArrays replaces the earlier array.
+	ReplaceArrays ArrayMerge = iota
+
+	// AppendArrays appends the elements of the later array to the earlier.
+	AppendArrays
+)
+
+// Layer is a source of TOML data for a Loader.
+type Layer interface {
+	// parse parses the data of the layer. It returns a nil parser if the
+	// layer has no data. `merged` holds the data of the earlier layers and
+	// `rt` is the type being loaded into, or nil if it is unknown.
+	parse(dec *Decoder, merged *parser, rt reflect.Type) (*parser, error)
+}
+
+type fileLayer struct {
+	fpath    string
+	optional bool
+}
+
+// FileLayer returns a layer that reads the TOML file at `fpath`.
+func FileLayer(fpath string) Layer {
+	return fileLayer{fpath: fpath}
+}
+
+// OptionalFileLayer is like FileLayer, except that a missing file is treated
+// as an empty layer instead of an error.
+func OptionalFileLayer(fpath string) Layer {
+	return fileLayer{fpath: fpath, optional: true}
+}
+
+func (l fileLayer) parse(
+	dec *Decoder, merged *parser, rt reflect.Type) (*parser, error) {
+
+	bs, err := ioutil.ReadFile(l.fpath)
+	if err != nil {
+		if l.optional && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	p, err := dec.parse(string(bs), l.fpath)
+	if p != nil {
+		p.files = append([]string{absPath(l.fpath)}, p.files...)
+	}
+	return p, err
+}
+
+type stringLayer struct {
+	name, data string
+}
+
+// StringLayer returns a layer with the TOML data given. The name identifies
+// the layer in positions and error messages.
+func StringLayer(name, data string) Layer {
+	return stringLayer{name, data}
+}
+
+func (l stringLayer) parse(
+	dec *Decoder, merged *parser, rt reflect.Type) (*parser, error) {
+
+	return dec.parse(l.data, l.name)
+}
+
+// Add adds layers to the loader. Each layer takes precedence over the
+// layers added before it.
+func (l *Loader) Add(layers ...Layer) {
+	l.layers = append(l.layers, layers...)
+}
+
+// Load parses and merges all layers, then decodes the result into the
+// pointer `v`, just like Decode would.
+func (l *Loader) Load(v interface{}) (MetaData, error) {
+	merged, err := l.merge(reflect.TypeOf(v))
+	if err != nil {
+		return MetaData{}, err
+	}
+	return l.decoder().unifyParsed(merged, v)
+}
+
+func (l *Loader) decoder() *Decoder {
+	if l.Decoder == nil {
+		return new(Decoder)
+	}
+	return l.Decoder
+}
+
+// merge parses every layer and merges them into a single parser's data,
+// which will be decoded into a value of type `rt`.
+func (l *Loader) merge(rt reflect.Type) (*parser, error) {
+	merged := newLayerParser()
+	seen := make(map[string]bool)
+	for _, layer := range l.layers {
+		p, err := layer.parse(l.decoder(), merged, rt)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			continue
+		}
+		if err := l.mergeTable(merged, p, merged.mapping, p.mapping,
+			Key{}); err != nil {
+
+			return nil, err
+		}
+		merged.warnings = append(merged.warnings, p.warnings...)
+		merged.files = append(merged.files, p.files...)
+		for _, key := range p.ordered {
+			if !seen[key.String()] {
+				seen[key.String()] = true
+				merged.ordered = append(merged.ordered, key)
+			}
+		}
+	}
+
+	// Replaced tables may have taken some keys with them.
+	md := MetaData{mapping: merged.mapping}
+	ordered := make([]Key, 0, len(merged.ordered))
+	for _, key := range merged.ordered {
+		if md.IsDefined(key...) {
+			ordered = append(ordered, key)
+		}
+	}
+	merged.ordered = ordered
+	return merged, nil
+}
+
+// mergeTable merges the table `src` of the layer `from` into the table `dst`
+// of `merged`, where both tables are found at `context`.
+func (l *Loader) mergeTable(merged, from *parser,
+	dst, src map[string]interface{}, context Key) error {
+
+	// Keys are merged in sorted order, so that the same conflict is
+	// reported every time.
+	for _, k := range sortedKeys(src) {
+		sv, key := src[k], context.add(k)
+		dv, exists := dst[k]
+		srcTable, srcIsTable := sv.(map[string]interface{})
+		dstTable, dstIsTable := dv.(map[string]interface{})
+		appendArrays := exists && l.Arrays == AppendArrays &&
+			(isArray(sv) || isArray(dv))
+		if (exists && srcIsTable != dstIsTable) ||
+			(appendArrays && isArray(sv) != isArray(dv)) {
+
+			return e("%s, key '%s': Cannot merge %s into %s defined "+
+				"in an earlier layer.", from.positions[key.String()].near(),
+				key, describe(sv), describe(dv))
+		}
+		if appendArrays {
+			sa, da := sv.([]interface{}), dv.([]interface{})
+			if len(sa) > 0 && len(da) > 0 &&
+				!typeEqual(typeOfValue(sa[0]), typeOfValue(da[0])) {
+
+				return e("%s, key '%s': Cannot append an array of type "+
+					"'%s' to an array of type '%s' defined in an earlier "+
+					"layer, since arrays must be homogeneous.",
+					from.positions[key.String()].near(), key,
+					typeOfValue(sa[0]), typeOfValue(da[0]))
+			}
+		}
+
+		lit, hasLit := from.literals[key.String()]
+		switch {
+		case exists && srcIsTable && l.Tables == MergeTables:
+			if err := l.mergeTable(merged, from, dstTable, srcTable,
+				key); err != nil {
+
+				return err
+			}
+		case appendArrays:
+			lit, hasLit = arrayLiteral(append(
+				literal(merged.literals, key, dv).Elements,
+				literal(from.literals, key, sv).Elements...)), true
+			dst[k] = append(dv.([]interface{}), sv.([]interface{})...)
+		default:
+			if dstIsTable {
+				dropMeta(merged, dstTable, key)
+			}
+			dst[k] = sv
+			if srcIsTable {
+				copyMeta(merged, from, srcTable, key)
+			}
+		}
+
+		if typ, ok := from.types[key.String()]; ok {
+			merged.types[key.String()] = typ
+		}
+		if pos, ok := from.positions[key.String()]; ok {
+			merged.positions[key.String()] = pos
+		}
+		delete(merged.literals, key.String())
+		if hasLit {
+			merged.literals[key.String()] = lit
+		}
+	}
+	return nil
+}
+
+// copyMeta copies the types, positions and literals of every key in `table`,
+// found at `context`, from the parser `from` to `merged`.
+func copyMeta(merged, from *parser, table map[string]interface{},
+	context Key) {
+
+	for k, v := range table {
+		key := context.add(k)
+		if typ, ok := from.types[key.String()]; ok {
+			merged.types[key.String()] = typ
+		}
+		if pos, ok := from.positions[key.String()]; ok {
+			merged.positions[key.String()] = pos
+		}
+		if lit, ok := from.literals[key.String()]; ok {
+			merged.literals[key.String()] = lit
+		}
+		if t, ok := v.(map[string]interface{}); ok {
+			copyMeta(merged, from, t, key)
+		}
+	}
+}
+
+// dropMeta forgets the types, positions and literals of every key in
+// `table`, found at `context`, which is being replaced.
+func dropMeta(merged *parser, table map[string]interface{}, context Key) {
+	for k, v := range table {
+		key := context.add(k)
+		delete(merged.types, key.String())
+		delete(merged.positions, key.String())
+		delete(merged.literals, key.String())
+		if t, ok := v.(map[string]interface{}); ok {
+			dropMeta(merged, t, key)
+		}
+	}
+}
+
+// newLayerParser returns a parser with no data, which holds the data of a
+// layer that isn't parsed from TOML, or of merged layers.
+func newLayerParser() *parser {
+	return &parser{
+		mapping:   make(map[string]interface{}),
+		types:     make(map[string]tomlType),
+		ordered:   make([]Key, 0),
+		positions: make(map[string]Position),
+		literals:  make(map[string]Literal),
+		secrets:   make(map[string]bool),
+	}
+}
+
+// setKey sets the key `key` of the parser `p`, which holds the data of a
+// layer, to `val`, creating tables as needed.
+func setKey(p *parser, key Key, val interface{}, typ tomlType,
+	pos Position) error {
+
+	table := p.mapping
+	for i, k := range key[:len(key)-1] {
+		switch t := table[k].(type) {
+		case map[string]interface{}:
+			table = t
+		case nil:
+			sub := make(map[string]interface{})
+			table[k] = sub
+			table = sub
+			p.types[key[:i+1].String()] = tomlHash
+			p.ordered = append(p.ordered, key[:i+1])
+		default:
+			return e("%s", keyMessage(key[:i+1], pos,
+				"Key has already been defined."))
+		}
+	}
+
+	last := key[len(key)-1]
+	if _, ok := table[last]; ok {
+		return e("%s", keyMessage(key, pos, "Key has already been defined."))
+	}
+	table[last] = val
+	p.types[key.String()] = typ
+	p.positions[key.String()] = pos
+	p.ordered = append(p.ordered, key)
+	return nil
+}
+
+func isArray(v interface{}) bool {
+	_, ok := v.([]interface{})
+	return ok
+}
+
+// describe names the kind of TOML value `v` is, for error messages.
+func describe(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "a table"
+	case []interface{}:
+		return "an array"
+	}
+	return "a value"
+}
@@ -0,0 +1,168 @@
+package toml
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var loaderDefaults = `
+title = "defaults"
+tags = ["base"]
+
+[database]
+server = "localhost"
+ports = [8001]
+connection_max = 10
+
+[servers.alpha]
+ip = "10.0.0.1"
+dc = "eqdc10"
+`
+
+var loaderSite = `
+title = "site"
+tags = ["site"]
+
+[database]
+server = "db.example.com"
+
+[servers.beta]
+ip = "10.0.0.2"
+`
+
+type loaderConfig struct {
+	Title    string
+	Tags     []string
+	Database struct {
+		Server        string
+		Ports         []int
+		ConnectionMax int `toml:"connection_max"`
+	}
+	Servers map[string]struct {
+		IP string
+		DC string
+	}
+}
+
+func TestLoaderMergesTables(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"host.toml": "[database]\nconnection_max = 500\n",
+	})
+
+	var l Loader
+	l.Add(StringLayer("defaults", loaderDefaults),
+		StringLayer("site", loaderSite),
+		FileLayer(filepath.Join(dir, "host.toml")),
+		OptionalFileLayer(filepath.Join(dir, "missing.toml")))
+
+	var conf loaderConfig
+	md, err := l.Load(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Title != "site" || conf.Database.Server != "db.example.com" ||
+		conf.Database.ConnectionMax != 500 || conf.Database.Ports[0] != 8001 {
+
+		t.Fatalf("Unexpected config: %+v", conf)
+	}
+	if !reflect.DeepEqual(conf.Tags, []string{"site"}) {
+		t.Fatalf("Expected arrays to be replaced, got %v", conf.Tags)
+	}
+	if len(conf.Servers) != 2 || conf.Servers["alpha"].DC != "eqdc10" {
+		t.Fatalf("Expected servers to be merged, got %+v", conf.Servers)
+	}
+
+	sources := map[string]string{
+		"title":                   "site:2",
+		"database.ports":          "defaults:7",
+		"database.connection_max": filepath.Join(dir, "host.toml") + ":2",
+		"servers.alpha.ip":        "defaults:11",
+		"servers.beta":            "site:8",
+		"nope":                    "",
+	}
+	for key, want := range sources {
+		if got := md.Source(strings.Split(key, ".")...); got != want {
+			t.Errorf("Expected source of '%s' to be %q, got %q", key, want, got)
+		}
+	}
+	if md.Type("database", "connection_max") != "Integer" {
+		t.Errorf("Expected merged types to be kept")
+	}
+}
+
+func TestLoaderMergeRules(t *testing.T) {
+	l := Loader{Tables: ReplaceTables, Arrays: AppendArrays}
+	l.Add(StringLayer("defaults", loaderDefaults),
+		StringLayer("site", loaderSite))
+
+	var conf loaderConfig
+	md, err := l.Load(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf.Tags, []string{"base", "site"}) {
+		t.Fatalf("Expected arrays to be appended, got %v", conf.Tags)
+	}
+	if conf.Database.ConnectionMax != 0 || len(conf.Database.Ports) != 0 {
+		t.Fatalf("Expected the database table to be replaced, got %+v",
+			conf.Database)
+	}
+	if md.IsDefined("database", "ports") || md.Type("database", "ports") != "" {
+		t.Fatalf("Expected replaced keys to be forgotten")
+	}
+	for _, key := range md.Keys() {
+		if key.String() == "database.ports" {
+			t.Fatalf("Expected replaced keys to be forgotten")
+		}
+	}
+}
+
+func TestLoaderConflicts(t *testing.T) {
+	var l Loader
+	l.Add(StringLayer("defaults", loaderDefaults),
+		StringLayer("bad", "database = \"oops\""))
+
+	var conf loaderConfig
+	_, err := l.Load(&conf)
+	if err == nil {
+		t.Fatal("Expected an error when replacing a table with a value")
+	}
+	want := "Near line 1 of 'bad', key 'database': Cannot merge a value " +
+		"into a table defined in an earlier layer."
+	if err.Error() != want {
+		t.Fatalf("Expected %q but got %q", want, err.Error())
+	}
+}
+
+func TestLoaderAppendConflicts(t *testing.T) {
+	tests := []struct{ first, second, want string }{
+		{`x = "s"`, `x = [1, 2]`, "key 'x': Cannot merge an array into a " +
+			"value defined in an earlier layer."},
+		{`x = [1, 2]`, `x = "s"`, "key 'x': Cannot merge a value into an " +
+			"array defined in an earlier layer."},
+		{`x = [1]`, `x = ["a"]`, "key 'x': Cannot append an array of type " +
+			"'String' to an array of type 'Integer' defined in an earlier " +
+			"layer, since arrays must be homogeneous."},
+		{"a = 1\nb = 1\nc = [1]", "c = [\"a\"]\nb = [2]\na = [3]",
+			"key 'a': Cannot merge an array into a value defined in an " +
+				"earlier layer."},
+	}
+	for _, test := range tests {
+		// Conflicts are reported in the same order every time.
+		for i := 0; i < 10; i++ {
+			l := Loader{Arrays: AppendArrays}
+			l.Add(StringLayer("a", test.first),
+				StringLayer("b", test.second))
+
+			var v map[string]interface{}
+			_, err := l.Load(&v)
+			if err == nil || !strings.HasSuffix(err.Error(), test.want) {
+				t.Errorf("Expected %q but got %v", test.want, err)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeyMapper maps the name of a struct field to the TOML key it corresponds
+// to. It is only consulted for fields without a `toml` struct tag.
+//
+// SnakeCase, KebabCase and LowerCamelCase are provided, but any function
+// with this signature may be used.
+type KeyMapper func(field string) string
+
+// SnakeCase maps a Go field name to snake_case, e.g., `ConnectionMax` becomes
+// `connection_max` and `HTTPServer` becomes `http_server`.
+func SnakeCase(field string) string {
+	return strings.ToLower(strings.Join(splitWords(field), "_"))
+}
+
+// KebabCase maps a Go field name to kebab-case, e.g., `ConnectionMax` becomes
+// `connection-max`.
+func KebabCase(field string) string {
+	return strings.ToLower(strings.Join(splitWords(field), "-"))
+}
+
+// LowerCamelCase maps a Go field name to lowerCamelCase, e.g.,
+// `ConnectionMax` becomes `connectionMax` and `HTTPServer` becomes
+// `httpServer`.
+func LowerCamelCase(field string) string {
+	words := splitWords(field)
+	if len(words) == 0 {
+		return field
+	}
+	words[0] = strings.ToLower(words[0])
+	for i, w := range words[1:] {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i+1] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, "")
+}
+
+// fieldOpts describes how a struct field maps to a TOML key.
+type fieldOpts struct {
+	// the TOML key name of the field
+	name string
+
+	// whether the name comes from a `toml` struct tag
+	tagged bool
+
+	// whether the name comes from a `toml` or `json` struct tag, rather than
+	// the Go name of the field
+	named bool
+
+	// whether the field is excluded with a "-" tag
+	skip bool
+
+	// whether the encoder should leave out the field if it is empty
+	omitempty bool
+
+	// whether the value of the field is kept out of error messages and dumps
+	secret bool
+
+	// other TOML key names of the field, such as names it used to have
+	aliases []string
+
+	// whether using an alias, or the key name if there are no aliases, is
+	// reported as a warning
+	deprecated bool
+}
+
+// fieldOptions resolves the TOML key name and options of a struct field.
+// Tags have the form `toml:"name,opt1,opt2"`, like those of encoding/json.
+// The `toml` tag takes precedence; when it is absent and `useJSON` is true,
+// the `json` tag is used instead. Fields without a name in their tag use
+// their Go name, passed through `mapper` if it isn't nil.
+func fieldOptions(
+	sft reflect.StructField, mapper KeyMapper, useJSON bool) fieldOpts {
+
+	tag, isTOML := sft.Tag.Lookup("toml")
+	if !isTOML && useJSON {
+		tag = sft.Tag.Get("json")
+	}
+
+	var opts fieldOpts
+	if tag == "-" {
+		opts.skip = true
+		return opts
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		switch {
+		case opt == "omitempty":
+			opts.omitempty = true
+		case opt == "secret":
+			opts.secret = true
+		case opt == "deprecated":
+			opts.deprecated = true
+		case strings.HasPrefix(opt, "alias="):
+			opts.aliases = append(opts.aliases, opt[len("alias="):])
+		}
+	}
+	switch {
+	case len(parts[0]) > 0:
+		opts.name = parts[0]
+		opts.tagged = isTOML
+		opts.named = true
+	case mapper != nil:
+		opts.name = mapper(sft.Name)
+	default:
+		opts.name = sft.Name
+	}
+	return opts
+}
+
+// matchField finds the key in the table indexed by `ix` that sets the field
+// `f`, which is its key name or one of its aliases, as ix.match does. It
+// also returns whether the key is an alias. It is an error for more than one
+// of them to be in the table, in which case the key returned is the alias
+// that conflicts.
+func matchField(ix *keyIndex, f field) (key string, found, alias bool,
+	err error) {
+
+	key, found, err = ix.match(f.opts.name, f.folded)
+	if err != nil {
+		return "", false, false, err
+	}
+	for _, name := range f.opts.aliases {
+		k, ok, err := ix.match(name, "")
+		if err != nil {
+			return "", false, false, err
+		}
+		if !ok || (found && k == key) {
+			continue
+		}
+		if found {
+			return k, false, true, e("Keys '%s' and '%s' are names of the "+
+				"same value, so only one of them may be set.", key, k)
+		}
+		key, found, alias = k, true, true
+	}
+	return key, found, alias, nil
+}
+
+// splitWords splits a Go identifier into the words it is made of. A word
+// starts at every upper case letter following a lower case letter or digit,
+// and at the last upper case letter of an acronym followed by a lower case
+// letter. Underscores separate words and are dropped.
+func splitWords(name string) []string {
+	var words []string
+	var runes []rune
+	for len(name) > 0 {
+		r, size := utf8.DecodeRuneInString(name)
+		runes = append(runes, r)
+		name = name[size:]
+	}
+
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			words = append(words, string(runes[start:end]))
+		}
+		start = end
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			flush(i)
+			start = i + 1
+		case i > start && unicode.IsUpper(r):
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				flush(i)
+			} else if i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				flush(i)
+			}
+		}
+	}
+	flush(len(runes))
+	return words
+}
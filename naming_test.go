@@ -0,0 +1,75 @@
+package toml
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestKeyMappers(t *testing.T) {
+	tests := []struct {
+		field, snake, kebab, camel string
+	}{
+		{"ConnectionMax", "connection_max", "connection-max", "connectionMax"},
+		{"HTTPServer", "http_server", "http-server", "httpServer"},
+		{"IP", "ip", "ip", "ip"},
+		{"DB2Name", "db2_name", "db2-name", "db2Name"},
+		{"already_snake", "already_snake", "already-snake", "alreadySnake"},
+		{"Server", "server", "server", "server"},
+	}
+	for _, test := range tests {
+		if got := SnakeCase(test.field); got != test.snake {
+			t.Errorf("SnakeCase(%q) = %q, want %q", test.field, got, test.snake)
+		}
+		if got := KebabCase(test.field); got != test.kebab {
+			t.Errorf("KebabCase(%q) = %q, want %q", test.field, got, test.kebab)
+		}
+		if got := LowerCamelCase(test.field); got != test.camel {
+			t.Errorf("LowerCamelCase(%q) = %q, want %q",
+				test.field, got, test.camel)
+		}
+	}
+}
+
+func TestFoldKey(t *testing.T) {
+	words := []string{"name", "NAME", "Name", "nAmE", "naMe2", "kelvin",
+		"Kelvin", "Kelvin", "ſum", "sum", "SUM", "straße",
+		"STRASSE", "ǅ", "ǆ", "Ǆ", "σ", "ς", "Σ", "", "\xff", "\xfe"}
+	for _, a := range words {
+		for _, b := range words {
+			fold := foldKey(a) == foldKey(b)
+			if want := strings.EqualFold(a, b); fold != want {
+				t.Errorf("foldKey(%q) == foldKey(%q) is %v, want %v",
+					a, b, fold, want)
+			}
+		}
+	}
+}
+
+func TestFieldCacheConcurrent(t *testing.T) {
+	type config struct {
+		Name  string
+		Ports []int `toml:"ports"`
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v config
+			_, err := Decode("name = 'x'\nports = [1, 2]", &v)
+			if err == nil && (v.Name != "x" || len(v.Ports) != 2) {
+				err = e("Decoded %+v.", v)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package toml
+
+import (
+	"reflect"
+)
+
+// Optional holds a value of type T that may be absent from TOML data. It
+// decodes and encodes as the value it wraps, but also records whether the
+// value was present, which tells `retries = 0` apart from a missing
+// `retries` key.
+//
+// The zero value is an absent value.
+type Optional[T any] struct {
+	value T
+	set   bool
+}
+
+// Set stores `v` and marks the value as present.
+func (o *Optional[T]) Set(v T) {
+	o.value = v
+	o.set = true
+}
+
+// Value returns the wrapped value and whether it was present. An absent
+// value is the zero value of T.
+func (o Optional[T]) Value() (T, bool) {
+	return o.value, o.set
+}
+
+// IsSet returns true if the value was present.
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// optional is implemented by pointers to Optional values, giving the decoder
+// and encoder access to the value they wrap regardless of its type.
+type optional interface {
+	// setValue marks the value as present and returns it, addressable, so
+	// that it can be decoded into.
+	setValue() reflect.Value
+
+	// getValue returns the wrapped value and whether it is present.
+	getValue() (reflect.Value, bool)
+}
+
+func (o *Optional[T]) setValue() reflect.Value {
+	o.set = true
+	return reflect.ValueOf(&o.value).Elem()
+}
+
+func (o *Optional[T]) getValue() (reflect.Value, bool) {
+	return reflect.ValueOf(o.value), o.set
+}
+
+var optionalType = reflect.TypeOf((*optional)(nil)).Elem()
+
+// optionalElem returns the type wrapped by the Optional type `rt`, or nil if
+// `rt` isn't an Optional type.
+func optionalElem(rt reflect.Type) reflect.Type {
+	if rt.Kind() != reflect.Struct {
+		return nil
+	}
+	if !reflect.PtrTo(rt).Implements(optionalType) {
+		return nil
+	}
+	return reflect.New(rt).Interface().(optional).setValue().Type()
+}
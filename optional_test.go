@@ -0,0 +1,73 @@
+package toml
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeOptional(t *testing.T) {
+	var val struct {
+		Retries Optional[int]
+		Timeout Optional[int]
+		Name    Optional[*string]
+		Missing *int
+		Present *int
+	}
+	md, err := Decode("retries = 0\nname = \"andrew\"\npresent = 5", &val)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := val.Retries.Value(); !ok || v != 0 {
+		t.Errorf("Expected retries to be set to 0, got %d (%v)", v, ok)
+	}
+	if val.Timeout.IsSet() {
+		t.Errorf("Expected timeout to be absent")
+	}
+	if v, ok := val.Name.Value(); !ok || *v != "andrew" {
+		t.Errorf("Expected name to be set to 'andrew', got %v (%v)", v, ok)
+	}
+	if val.Missing != nil {
+		t.Errorf("Expected missing pointer to stay nil")
+	}
+	if val.Present == nil || *val.Present != 5 {
+		t.Errorf("Expected present pointer to point to 5, got %v", val.Present)
+	}
+	if len(md.Undecoded()) != 0 {
+		t.Errorf("Expected all keys to be decoded, got %v", md.Undecoded())
+	}
+}
+
+func TestDecodeOptionalMismatch(t *testing.T) {
+	var val struct {
+		Retries Optional[int]
+	}
+	if _, err := Decode(`retries = "many"`, &val); err == nil {
+		t.Fatal("Expected a type mismatch")
+	}
+	_, err := DecodeStrict(`retries = 3`, &val, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckType(map[string]interface{}{"retries": "many"}, val, nil)
+	if err == nil {
+		t.Fatal("Expected a strict type mismatch")
+	}
+}
+
+func TestEncodeOptional(t *testing.T) {
+	var v struct {
+		Name     Optional[string]
+		Nickname Optional[string]
+	}
+	v.Name.Set("andrew")
+
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Name = \"andrew\"\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q but got %q", expected, buf.String())
+	}
+}
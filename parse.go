@@ -2,7 +2,10 @@ package toml
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -14,9 +17,24 @@ type parser struct {
 	types   map[string]tomlType
 	lx      *lexer
 
+	// the decoder whose options apply while parsing
+	dec *Decoder
+
 	// A list of keys in the order that they appear in the TOML data.
 	ordered []Key
 
+	// A map of 'key.group.names' to where they were defined.
+	positions map[string]Position
+
+	// A map of 'key.group.names' to how their values were written.
+	literals map[string]Literal
+
+	// The 'key.group.names' whose values were decoded into secret fields.
+	secrets map[string]bool
+
+	// The warnings found while parsing.
+	warnings []Warning
+
 	// the full key for the current hash in scope
 	context Key
 
@@ -26,6 +44,16 @@ type parser struct {
 	// rough approximation of line number
 	approxLine int
 
+	// the file being parsed, if the data came from a file
+	file string
+
+	// the absolute paths of the files being parsed, innermost last, used to
+	// detect include cycles
+	including []string
+
+	// the absolute paths of every file read, in the order they were read
+	files []string
+
 	// A map of 'key.group.names' to whether they were created implicitly.
 	implicits map[string]bool
 }
@@ -37,6 +65,12 @@ func (pe parseError) Error() string {
 }
 
 func parse(data string) (p *parser, err error) {
+	return new(Decoder).parse(data, "")
+}
+
+// parse parses TOML data read from the file `fpath`, which is empty if the
+// data didn't come from a file.
+func (dec *Decoder) parse(data, fpath string) (p *parser, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			var ok bool
@@ -51,9 +85,23 @@ func parse(data string) (p *parser, err error) {
 		mapping:   make(map[string]interface{}),
 		types:     make(map[string]tomlType),
 		lx:        lex(data),
+		dec:       dec,
 		ordered:   make([]Key, 0),
+		positions: make(map[string]Position),
+		literals:  make(map[string]Literal),
+		secrets:   make(map[string]bool),
 		implicits: make(map[string]bool),
+		file:      fpath,
+	}
+	if len(fpath) > 0 {
+		p.including = append(p.including, absPath(fpath))
 	}
+	p.parseAll()
+	return p, nil
+}
+
+// parseAll consumes all items of the current lexer.
+func (p *parser) parseAll() {
 	for {
 		item := p.next()
 		if item.typ == itemEOF {
@@ -61,16 +109,31 @@ func parse(data string) (p *parser, err error) {
 		}
 		p.topLevel(item)
 	}
-
-	return p, nil
 }
 
 func (p *parser) panic(format string, v ...interface{}) {
-	msg := fmt.Sprintf("Near line %d, key '%s': %s",
-		p.approxLine, p.current(), fmt.Sprintf(format, v...))
+	pos := Position{File: p.file, Line: p.approxLine}
+	msg := fmt.Sprintf("%s, key '%s': %s",
+		pos.near(), p.current(), fmt.Sprintf(format, v...))
 	panic(parseError(msg))
 }
 
+// warn records a warning about the current key.
+func (p *parser) warn(kind WarningKind, format string, v ...interface{}) {
+	key := p.context.add(p.currentKey)
+	p.warnings = append(p.warnings, Warning{
+		Kind:     kind,
+		Key:      key,
+		Position: p.position(p.approxLine),
+		Message:  fmt.Sprintf(format, v...),
+	})
+}
+
+// position returns a position on line `line` of the file being parsed.
+func (p *parser) position(line int) Position {
+	return Position{File: p.file, Line: line}
+}
+
 func (p *parser) next() item {
 	it := p.lx.nextItem()
 	if it.typ == itemError {
@@ -113,15 +176,24 @@ func (p *parser) topLevel(item item) {
 		p.establishContext(key)
 		p.setType("", tomlHash)
 		p.ordered = append(p.ordered, key)
+		p.positions[key.String()] = p.position(p.approxLine)
 	case itemKeyStart:
 		kname := p.expect(itemText)
 		p.currentKey = kname.val
 		p.approxLine = kname.line
 
-		val, typ := p.value(p.next())
+		val, typ, lit := p.value(p.next())
+		if p.isInclude() {
+			p.include(val)
+			p.currentKey = ""
+			return
+		}
 		p.setValue(p.currentKey, val)
 		p.setType(p.currentKey, typ)
 		p.ordered = append(p.ordered, p.context.add(p.currentKey))
+		p.positions[p.context.add(p.currentKey).String()] =
+			p.position(kname.line)
+		p.literals[p.context.add(p.currentKey).String()] = lit
 
 		p.currentKey = ""
 	default:
@@ -129,6 +201,87 @@ func (p *parser) topLevel(item item) {
 	}
 }
 
+// isInclude returns true if the current key is the include directive.
+func (p *parser) isInclude() bool {
+	return len(p.dec.IncludeKey) > 0 && len(p.context) == 0 &&
+		p.currentKey == p.dec.IncludeKey
+}
+
+// include parses the files named by the value of an include directive, as
+// if their contents appeared in place of the directive. Each value is a path
+// or glob pattern, relative to the directory of the including file.
+func (p *parser) include(val interface{}) {
+	var patterns []string
+	switch val := val.(type) {
+	case string:
+		patterns = []string{val}
+	case []interface{}:
+		for _, v := range val {
+			s, ok := v.(string)
+			if !ok {
+				p.panic("Expected an array of file names to include, but "+
+					"found '%T' in it.", v)
+			}
+			patterns = append(patterns, s)
+		}
+	default:
+		p.panic("Expected a file name or an array of file names to "+
+			"include, but got '%T'.", val)
+	}
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(p.file), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			p.panic("Bad include pattern '%s': %s", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			p.panic("Included file '%s' does not exist.", pattern)
+		}
+		for _, fpath := range matches {
+			p.includeFile(fpath)
+		}
+	}
+}
+
+// includeFile parses the file at `fpath` into the current mapping. All of
+// its keys are subject to the same rules as keys in the including file, so
+// keys may not be defined twice across files.
+func (p *parser) includeFile(fpath string) {
+	abs := absPath(fpath)
+	for _, f := range p.including {
+		if f == abs {
+			p.panic("Including '%s' would create a cycle: %s.",
+				fpath, strings.Join(append(p.including, abs), " -> "))
+		}
+	}
+	bs, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		p.panic("Cannot include '%s': %s", fpath, err)
+	}
+
+	lx, context, file, line := p.lx, p.context, p.file, p.approxLine
+	p.lx, p.context, p.file = lex(string(bs)), make(Key, 0), fpath
+	p.including = append(p.including, abs)
+	p.files = append(p.files, abs)
+
+	p.parseAll()
+
+	p.lx, p.context, p.file, p.approxLine = lx, context, file, line
+	p.including = p.including[0 : len(p.including)-1]
+}
+
+// absPath returns an absolute version of `fpath`, or `fpath` itself if that
+// fails.
+func absPath(fpath string) string {
+	if abs, err := filepath.Abs(fpath); err == nil {
+		return abs
+	}
+	return fpath
+}
+
 func (p *parser) replaceEscapes(str string) string {
 	var replaced []rune
 	s := []byte(str)
@@ -189,27 +342,83 @@ func (p *parser) replaceEscapes(str string) string {
 	return string(replaced)
 }
 
+// expandEnv replaces references to environment variables in a string.
+// `${VAR}` is replaced by the value of `VAR`, and `${VAR:-default}` by
+// `default` if `VAR` is undefined or empty. `$$` is replaced by a single `$`.
+// Any other `$` is left alone. Referring to an undefined variable without a
+// default is an error.
+func (p *parser) expandEnv(str string) string {
+	lookup := p.dec.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	var expanded []byte
+	for r := 0; r < len(str); r++ {
+		if str[r] != '$' || r+1 >= len(str) {
+			expanded = append(expanded, str[r])
+			continue
+		}
+		switch str[r+1] {
+		default:
+			expanded = append(expanded, str[r])
+		case '$':
+			expanded = append(expanded, '$')
+			r += 1
+		case '{':
+			end := strings.IndexByte(str[r+2:], '}')
+			if end < 0 {
+				p.panic("Unterminated environment variable reference in "+
+					"'%s'.", str)
+			}
+			ref := str[r+2 : r+2+end]
+			name, def, hasDef := strings.Cut(ref, ":-")
+			if len(name) == 0 {
+				p.panic("Empty environment variable reference in '%s'.", str)
+			}
+			val, ok := lookup(name)
+			if hasDef && len(val) == 0 {
+				val, ok = def, true
+			}
+			if !ok {
+				p.panic("Environment variable '%s' is not defined.", name)
+			}
+			expanded = append(expanded, val...)
+			r += 2 + end
+		}
+	}
+	return string(expanded)
+}
+
 // value translates an expected value from the lexer into a Go value wrapped
-// as an empty interface.
-func (p *parser) value(it item) (interface{}, tomlType) {
+// as an empty interface, along with how the value was written.
+func (p *parser) value(it item) (interface{}, tomlType, Literal) {
 	switch it.typ {
 	case itemString:
-		return p.replaceEscapes(it.val), p.typeOfPrimitive(it)
+		s := p.replaceEscapes(it.val)
+		if p.dec.ExpandEnv {
+			s = p.expandEnv(s)
+		}
+		return s, p.typeOfPrimitive(it), literalOfItem(it)
 	case itemRawString:
-		return it.val, p.typeOfPrimitive(it)
+		return it.val, p.typeOfPrimitive(it), literalOfItem(it)
 	case itemBool:
 		switch it.val {
 		case "true":
-			return true, p.typeOfPrimitive(it)
+			return true, p.typeOfPrimitive(it), literalOfItem(it)
 		case "false":
-			return false, p.typeOfPrimitive(it)
+			return false, p.typeOfPrimitive(it), literalOfItem(it)
 		}
 		p.bug("Expected boolean value, but got '%s'.", it.val)
 	case itemInteger:
 		num, err := strconv.ParseInt(it.val, 10, 64)
 		if err != nil {
 			if e, ok := err.(*strconv.NumError); ok &&
-				e.Err == strconv.ErrRange {
+				e.Err == strconv.ErrRange && p.dec.BigNumbers {
+
+				return parseBigInt(it.val), p.typeOfPrimitive(it),
+					literalOfItem(it)
+			} else if ok && e.Err == strconv.ErrRange {
 
 				p.panic("Integer '%s' is out of the range of 64-bit "+
 					"signed integers.", it.val)
@@ -217,9 +426,19 @@ func (p *parser) value(it item) (interface{}, tomlType) {
 				p.bug("Expected integer value, but got '%s'.", it.val)
 			}
 		}
-		return num, p.typeOfPrimitive(it)
+		return num, p.typeOfPrimitive(it), literalOfItem(it)
 	case itemFloat:
 		num, err := strconv.ParseFloat(it.val, 64)
+		if f, ok := parseBigFloat(it.val, num, err); ok {
+			if p.dec.BigNumbers {
+				return f, p.typeOfPrimitive(it), literalOfItem(it)
+			}
+			if err == nil {
+				p.warn(LossyConversion, "Float %s is rounded to %s, the "+
+					"nearest 64-bit float.", it.val,
+					strconv.FormatFloat(num, 'g', -1, 64))
+			}
+		}
 		if err != nil {
 			if e, ok := err.(*strconv.NumError); ok &&
 				e.Err == strconv.ErrRange {
@@ -230,16 +449,17 @@ func (p *parser) value(it item) (interface{}, tomlType) {
 				p.bug("Expected float value, but got '%s'.", it.val)
 			}
 		}
-		return num, p.typeOfPrimitive(it)
+		return num, p.typeOfPrimitive(it), literalOfItem(it)
 	case itemDatetime:
 		t, err := time.Parse("2006-01-02T15:04:05Z", it.val)
 		if err != nil {
 			p.bug("Expected Zulu formatted DateTime, but got '%s'.", it.val)
 		}
-		return t, p.typeOfPrimitive(it)
+		return t, p.typeOfPrimitive(it), literalOfItem(it)
 	case itemArray:
 		array := make([]interface{}, 0)
 		types := make([]tomlType, 0)
+		lits := make([]Literal, 0)
 
 		for it = p.next(); it.typ != itemArrayEnd; it = p.next() {
 			if it.typ == itemCommentStart {
@@ -247,11 +467,12 @@ func (p *parser) value(it item) (interface{}, tomlType) {
 				continue
 			}
 
-			val, typ := p.value(it)
+			val, typ, lit := p.value(it)
 			array = append(array, val)
 			types = append(types, typ)
+			lits = append(lits, lit)
 		}
-		return array, p.typeOfArray(types)
+		return array, p.typeOfArray(types), arrayLiteral(lits)
 	}
 	p.bug("Unexpected value type: %s", it.typ)
 	panic("unreachable")
@@ -376,8 +597,9 @@ func (p *parser) current() string {
 }
 
 func (p *parser) panicf(format string, v ...interface{}) {
-	msg := fmt.Sprintf("Near line %d (last key parsed '%s'): %s",
-		p.approxLine, p.current(), fmt.Sprintf(format, v...))
+	pos := Position{File: p.file, Line: p.approxLine}
+	msg := fmt.Sprintf("%s (last key parsed '%s'): %s",
+		pos.near(), p.current(), fmt.Sprintf(format, v...))
 	panic(parseError(msg))
 }
 
@@ -1,6 +1,9 @@
 package toml
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -61,3 +64,149 @@ func printMap(m map[string]interface{}, depth int) {
 		}
 	}
 }
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{
+		"HOST":  "db.example.com",
+		"EMPTY": "",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	var val struct {
+		Server   string
+		Port     string
+		Empty    string
+		Price    string
+		Literal  string
+		Unbraced string
+	}
+	dec := NewDecoder(strings.NewReader(`
+server = "${HOST}"
+port = "${PORT:-5432}"
+empty = "${EMPTY:-fallback}"
+price = "$$5 and $${HOST}"
+literal = '${HOST}'
+unbraced = "$HOST"
+`))
+	dec.ExpandEnv = true
+	dec.LookupEnv = lookup
+	if _, err := dec.Decode(&val); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct{ got, want string }{
+		{val.Server, "db.example.com"},
+		{val.Port, "5432"},
+		{val.Empty, "fallback"},
+		{val.Price, "$5 and ${HOST}"},
+		{val.Literal, "${HOST}"},
+		{val.Unbraced, "$HOST"},
+	}
+	for _, e := range expected {
+		if e.got != e.want {
+			t.Errorf("Expected %q but got %q", e.want, e.got)
+		}
+	}
+
+	dec = NewDecoder(strings.NewReader("[db]\npassword = \"${DB_PASSWORD}\""))
+	dec.ExpandEnv = true
+	dec.LookupEnv = lookup
+	_, err := dec.Decode(&val)
+	if err == nil {
+		t.Fatal("Expected an error for an undefined variable")
+	}
+	want := "Near line 2, key 'db.password': Environment variable " +
+		"'DB_PASSWORD' is not defined."
+	if err.Error() != want {
+		t.Fatalf("Expected %q but got %q", want, err.Error())
+	}
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, contents := range files {
+		fpath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fpath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.toml":          "include = [\"conf.d/*.toml\"]\ntitle = \"main\"\n",
+		"conf.d/a.toml":      "[database]\nserver = \"192.168.1.1\"\n",
+		"conf.d/b.toml":      "include = \"../servers/alpha.toml\"\n",
+		"servers/alpha.toml": "\n[servers.alpha]\nip = \"10.0.0.1\"\n",
+	})
+
+	var val struct {
+		Title    string
+		Database struct{ Server string }
+		Servers  map[string]struct{ IP string }
+	}
+	dec := NewDecoder(nil)
+	dec.IncludeKey = "include"
+	md, err := dec.DecodeFile(filepath.Join(dir, "main.toml"), &val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Title != "main" || val.Database.Server != "192.168.1.1" ||
+		val.Servers["alpha"].IP != "10.0.0.1" {
+
+		t.Fatalf("Unexpected value: %+v", val)
+	}
+	if md.IsDefined("include") {
+		t.Fatalf("The include directive should not be a key.")
+	}
+
+	pos := md.Position("servers", "alpha", "ip")
+	if pos.File != filepath.Join(dir, "conf.d/../servers/alpha.toml") ||
+		pos.Line != 3 {
+
+		t.Fatalf("Unexpected position for 'servers.alpha.ip': %+v", pos)
+	}
+	pos = md.Position("title")
+	if pos.File != filepath.Join(dir, "main.toml") || pos.Line != 2 {
+		t.Fatalf("Unexpected position for 'title': %+v", pos)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"dup.toml":     "include = \"dup2.toml\"\ntitle = \"main\"\n",
+		"dup2.toml":    "title = \"again\"\n",
+		"cycle.toml":   "include = [\"cycle2.toml\"]\n",
+		"cycle2.toml":  "include = [\"cycle.toml\"]\n",
+		"missing.toml": "include = [\"nope.toml\"]\n",
+	})
+
+	tests := []struct {
+		file, err string
+	}{
+		{"dup.toml", "Near line 2 of '" + filepath.Join(dir, "dup.toml") +
+			"', key 'title': Key 'title' has already been defined."},
+		{"cycle.toml", "would create a cycle"},
+		{"missing.toml", "does not exist"},
+	}
+	for _, test := range tests {
+		var val interface{}
+		dec := NewDecoder(nil)
+		dec.IncludeKey = "include"
+		_, err := dec.DecodeFile(filepath.Join(dir, test.file), &val)
+		if err == nil {
+			t.Fatalf("Expected an error decoding '%s'.", test.file)
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("Expected error containing %q but got %q",
+				test.err, err.Error())
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package query
+
+import (
+	"math/big"
+	"strings"
+	"time"
+)
+
+// expr is an expression in a filter.
+type expr interface {
+	// eval evaluates the expression for the value `at`, which `@` refers to.
+	// It returns nil if the expression refers to a value that isn't defined.
+	eval(at interface{}) interface{}
+}
+
+// pathExpr is `@` followed by the steps of a path.
+type pathExpr []step
+
+func (e pathExpr) eval(at interface{}) interface{} {
+	nodes := []node{{nil, at}}
+	for _, s := range e {
+		var next []node
+		for _, n := range nodes {
+			next = s.apply(n, next)
+		}
+		nodes = next
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0].value
+}
+
+// literalExpr is a number, string or boolean.
+type literalExpr struct {
+	value interface{}
+}
+
+func (e literalExpr) eval(at interface{}) interface{} {
+	return e.value
+}
+
+type andExpr struct {
+	left, right expr
+}
+
+func (e andExpr) eval(at interface{}) interface{} {
+	return truthy(e.left.eval(at)) && truthy(e.right.eval(at))
+}
+
+type orExpr struct {
+	left, right expr
+}
+
+func (e orExpr) eval(at interface{}) interface{} {
+	return truthy(e.left.eval(at)) || truthy(e.right.eval(at))
+}
+
+type compareExpr struct {
+	op          string
+	left, right expr
+}
+
+func (e compareExpr) eval(at interface{}) interface{} {
+	c, ok := compare(e.left.eval(at), e.right.eval(at))
+	if !ok {
+		return e.op == "!="
+	}
+	switch e.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+// truthy returns true if the value of an expression is defined and isn't
+// false.
+func truthy(v interface{}) bool {
+	b, ok := v.(bool)
+	return v != nil && (!ok || b)
+}
+
+// compare compares two values, returning a negative number, zero or a
+// positive number if `a` is less than, equal to or greater than `b`. It
+// returns false if the values can't be compared, such as a string with a
+// number or an undefined value with anything.
+func compare(a, b interface{}) (int, bool) {
+	if isBig(a) || isBig(b) {
+		x, xok := bigFloat(a)
+		y, yok := bigFloat(b)
+		return x.Cmp(y), xok && yok
+	}
+
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			return compareInt(a, b), true
+		case float64:
+			return compareFloat(float64(a), b), true
+		}
+	case float64:
+		switch b := b.(type) {
+		case int64:
+			return compareFloat(a, float64(b)), true
+		case float64:
+			return compareFloat(a, b), true
+		}
+	case string:
+		switch b := b.(type) {
+		case string:
+			return strings.Compare(a, b), true
+		case time.Time:
+			if t, err := time.Parse(time.RFC3339, a); err == nil {
+				return compareTime(t, b), true
+			}
+		}
+	case time.Time:
+		switch b := b.(type) {
+		case time.Time:
+			return compareTime(a, b), true
+		case string:
+			if t, err := time.Parse(time.RFC3339, b); err == nil {
+				return compareTime(a, t), true
+			}
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, true
+			case b:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+// isBig returns true if `v` is a number decoded with the BigNumbers option.
+func isBig(v interface{}) bool {
+	switch v.(type) {
+	case *big.Int, *big.Float:
+		return true
+	}
+	return false
+}
+
+// bigFloat returns the number `v` as a *big.Float, or false if it isn't a
+// number.
+func bigFloat(v interface{}) (*big.Float, bool) {
+	switch v := v.(type) {
+	case int64:
+		return new(big.Float).SetInt64(v), true
+	case float64:
+		return new(big.Float).SetFloat64(v), true
+	case *big.Int:
+		return new(big.Float).SetInt(v), true
+	case *big.Float:
+		return v, true
+	}
+	return new(big.Float), false
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a == b:
+		return 0
+	case a < b:
+		return -1
+	}
+	return 1
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a == b:
+		return 0
+	case a < b:
+		return -1
+	}
+	return 1
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Equal(b):
+		return 0
+	case a.Before(b):
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,363 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parser parses the text of a query.
+type parser struct {
+	expr string
+	pos  int
+}
+
+// stopChars end the names of keys.
+const stopChars = " \t.[]()\"'=!<>&|"
+
+func parse(expr string) ([]step, error) {
+	p := &parser{expr: expr}
+	p.skipSpace()
+	root := p.consume("$")
+
+	var steps []step
+	for p.skipSpace(); !p.eof(); p.skipSpace() {
+		var s step
+		var err error
+		switch {
+		case p.consume(".."):
+			if p.peek() == '[' {
+				s, err = p.bracket()
+			} else {
+				s, err = p.dotted()
+			}
+			s = recursiveStep{s}
+		case p.consume("."):
+			s, err = p.dotted()
+		case p.peek() == '[':
+			s, err = p.bracket()
+		case len(steps) == 0 && !root:
+			s, err = p.dotted()
+		default:
+			return nil, p.errorf("Unexpected '%c'", p.peek())
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+	if len(steps) == 0 && !root {
+		return nil, fmt.Errorf("Query '%s' is empty.", expr)
+	}
+	return steps, nil
+}
+
+func (p *parser) errorf(format string, v ...interface{}) error {
+	return fmt.Errorf("Query '%s': %s at offset %d.", p.expr,
+		fmt.Sprintf(format, v...), p.pos)
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.expr)
+}
+
+// peek returns the next byte, or 0 at the end of the query.
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.expr[p.pos]
+}
+
+// consume skips `s` and returns true if the query continues with it.
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.expr[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	p.skipSpace()
+	if !p.consume(s) {
+		if p.eof() {
+			return p.errorf("Expected '%s' but found the end", s)
+		}
+		return p.errorf("Expected '%s' but found '%c'", s, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// dotted parses the step after a dot: a name or a wildcard.
+func (p *parser) dotted() (step, error) {
+	if p.consume("*") {
+		return wildcardStep{}, nil
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	return childStep(name), nil
+}
+
+// name parses the name of a key, which is bare or quoted.
+func (p *parser) name() (string, error) {
+	switch p.peek() {
+	case '"', '\'':
+		return p.quoted()
+	}
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(stopChars, rune(p.peek())) {
+		p.pos++
+	}
+	if p.pos == start {
+		if p.eof() {
+			return "", p.errorf("Expected a key but found the end")
+		}
+		return "", p.errorf("Expected a key but found '%c'", p.peek())
+	}
+	return p.expr[start:p.pos], nil
+}
+
+// quoted parses a string in double quotes, with the escapes of TOML
+// strings, or in single quotes, without escapes.
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	if p.consume("'") {
+		end := strings.IndexByte(p.expr[p.pos:], '\'')
+		if end < 0 {
+			return "", p.errorf("Unterminated string")
+		}
+		p.pos += end + 1
+		return p.expr[start+1 : p.pos-1], nil
+	}
+
+	for p.pos++; !p.eof() && p.peek() != '"'; p.pos++ {
+		if p.peek() == '\\' {
+			p.pos++
+		}
+	}
+	if p.eof() {
+		p.pos = start
+		return "", p.errorf("Unterminated string")
+	}
+	p.pos++
+	key, err := toml.ParseKey(p.expr[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return "", p.errorf("Invalid string")
+	}
+	return key[0], nil
+}
+
+// bracket parses a step in brackets.
+func (p *parser) bracket() (step, error) {
+	p.consume("[")
+	p.skipSpace()
+
+	var s step
+	var err error
+	switch c := p.peek(); {
+	case p.consume("*"):
+		s = wildcardStep{}
+	case p.consume("?"):
+		s, err = p.filter()
+	case c == '"' || c == '\'':
+		var name string
+		name, err = p.quoted()
+		s = childStep(name)
+	default:
+		s, err = p.index()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// index parses an index or a slice of an array.
+func (p *parser) index() (step, error) {
+	start, hasStart, err := p.integer()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.consume(":") {
+		if !hasStart {
+			return nil, p.errorf("Expected an index")
+		}
+		return indexStep(start), nil
+	}
+	p.skipSpace()
+	end, hasEnd, err := p.integer()
+	if err != nil {
+		return nil, err
+	}
+	return sliceStep{start, end, hasStart, hasEnd}, nil
+}
+
+// integer parses an integer, if there is one.
+func (p *parser) integer() (int, bool, error) {
+	start := p.pos
+	p.consume("-")
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(p.expr[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false, p.errorf("Invalid index")
+	}
+	return n, true, nil
+}
+
+// filter parses a filter in parentheses.
+func (p *parser) filter() (step, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return filterStep{e}, nil
+}
+
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	for err == nil {
+		p.skipSpace()
+		if !p.consume("||") {
+			break
+		}
+		var right expr
+		if right, err = p.and(); err == nil {
+			left = orExpr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (expr, error) {
+	left, err := p.comparison()
+	for err == nil {
+		p.skipSpace()
+		if !p.consume("&&") {
+			break
+		}
+		var right expr
+		if right, err = p.comparison(); err == nil {
+			left = andExpr{left, right}
+		}
+	}
+	return left, err
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func (p *parser) comparison() (expr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range operators {
+		if p.consume(op) {
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return compareExpr{op, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) operand() (expr, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case p.consume("("):
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case p.consume("@"):
+		return p.path()
+	case c == '"' || c == '\'':
+		s, err := p.quoted()
+		return literalExpr{s}, err
+	case p.consume("true"):
+		return literalExpr{true}, nil
+	case p.consume("false"):
+		return literalExpr{false}, nil
+	case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+		return p.number()
+	case p.eof():
+		return nil, p.errorf("Expected a value but found the end")
+	}
+	return nil, p.errorf("Expected a value but found '%c'", p.peek())
+}
+
+// path parses the steps following `@`.
+func (p *parser) path() (expr, error) {
+	var steps pathExpr
+	for {
+		var s step
+		var err error
+		switch {
+		case p.consume(".."):
+			if p.peek() == '[' {
+				s, err = p.bracket()
+			} else {
+				s, err = p.dotted()
+			}
+			s = recursiveStep{s}
+		case p.consume("."):
+			s, err = p.dotted()
+		case p.peek() == '[':
+			s, err = p.bracket()
+		default:
+			return steps, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+}
+
+// number parses an integer or a float, written as in TOML data.
+func (p *parser) number() (expr, error) {
+	start := p.pos
+	for !p.eof() && strings.ContainsRune("+-0123456789._eE", rune(p.peek())) {
+		p.pos++
+	}
+	lit := strings.Replace(p.expr[start:p.pos], "_", "", -1)
+	if n, err := strconv.ParseInt(lit, 10, 64); err == nil {
+		return literalExpr{n}, nil
+	}
+	if f, err := strconv.ParseFloat(lit, 64); err == nil {
+		return literalExpr{f}, nil
+	}
+	p.pos = start
+	return nil, p.errorf("Invalid number")
+}
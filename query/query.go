@@ -0,0 +1,290 @@
+// Package query selects values from decoded TOML data with path expressions,
+// such as `servers.*.ip` or `database.ports[0]`.
+//
+// A query is a sequence of steps, each of which selects values below the
+// values selected by the step before it, starting from the top level table.
+// It may start with `$`, which stands for the top level table. The steps are:
+//
+//	name, .name        the key `name` of a table
+//	."a.b", ['a.b']    a key written as a TOML string or in single quotes
+//	*, .*, [*]         every value in a table or array
+//	[2], [-1]          an element of an array, counting from the end if
+//	                   negative
+//	[1:3], [:2], [1:]  a slice of an array
+//	..name, ..*        a step applied to a value and everything below it,
+//	                   at any depth
+//	[?(filter)]        every value in a table or array for which the filter
+//	                   holds
+//
+// Filters compare values with `==`, `!=`, `<`, `<=`, `>` and `>=`, and
+// combine comparisons with `&&`, `||` and parentheses. Inside a filter, `@`
+// is the value being tested, and `@.name` a key in it. Literals are numbers,
+// strings in double or single quotes, `true` and `false`. Strings compare
+// with datetimes as RFC 3339 datetimes. A value on its own, such as
+// `[?(@.enabled)]`, holds if it is defined and isn't false. For example:
+//
+//	servers[?(@.dc == "eqdc10" && @.ports[0] > 8000)].ip
+//
+// Tables are traversed in the sorted order of their keys, since the order of
+// keys in TOML data isn't kept by the decoder.
+package query
+
+import (
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Query is a compiled path expression. It is safe for concurrent use.
+type Query struct {
+	expr  string
+	steps []step
+}
+
+// Match is a value selected by a query.
+type Match struct {
+	// The full key of the value. Elements of arrays are named by their index,
+	// such as {"database", "ports", "0"}.
+	Key toml.Key
+
+	// The value, as decoded into an interface{}.
+	Value interface{}
+
+	// The TOML type of the value, such as "Integer" or "Hash".
+	Type string
+
+	// Where the value was defined, if it is known. See ExecuteMeta.
+	Position toml.Position
+}
+
+// Compile parses a query. See the package documentation for the syntax.
+func Compile(expr string) (*Query, error) {
+	steps, err := parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{expr: expr, steps: steps}, nil
+}
+
+// MustCompile is like Compile, except that it panics if the query can't be
+// parsed.
+func MustCompile(expr string) *Query {
+	q, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// String returns the query as it was written.
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Execute runs the query over TOML data decoded into a map, returning the
+// values selected in order. The types of the values are inferred from their
+// Go types, which tells apart all TOML types.
+func (q *Query) Execute(data map[string]interface{}) []Match {
+	nodes := []node{{toml.Key{}, data}}
+	for _, s := range q.steps {
+		var next []node
+		for _, n := range nodes {
+			next = s.apply(n, next)
+		}
+		nodes = next
+	}
+
+	matches := make([]Match, len(nodes))
+	for i, n := range nodes {
+		matches[i] = Match{Key: n.key, Value: n.value, Type: typeOf(n.value)}
+	}
+	return matches
+}
+
+// ExecuteMeta is like Execute, except that the types and positions of the
+// values are taken from the MetaData of the decoded data where it has them.
+func (q *Query) ExecuteMeta(
+	data map[string]interface{}, md toml.MetaData) []Match {
+
+	matches := q.Execute(data)
+	for i, m := range matches {
+		if typ := md.Type(m.Key...); len(typ) > 0 {
+			matches[i].Type = typ
+		}
+		matches[i].Position = md.Position(m.Key...)
+	}
+	return matches
+}
+
+// node is a value reached by a query, along with its key.
+type node struct {
+	key   toml.Key
+	value interface{}
+}
+
+// child returns the node for the value `v` found at `piece` below `n`.
+func (n node) child(piece string, v interface{}) node {
+	key := make(toml.Key, len(n.key), len(n.key)+1)
+	copy(key, n.key)
+	return node{append(key, piece), v}
+}
+
+// children returns the values in the table or array `n`, in order.
+func (n node) children() []node {
+	switch v := n.value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		nodes := make([]node, len(keys))
+		for i, k := range keys {
+			nodes[i] = n.child(k, v[k])
+		}
+		return nodes
+	case []interface{}:
+		nodes := make([]node, len(v))
+		for i, elem := range v {
+			nodes[i] = n.child(strconv.Itoa(i), elem)
+		}
+		return nodes
+	}
+	return nil
+}
+
+// descendants appends `n` and every value below it to `out`, depth first.
+func (n node) descendants(out []node) []node {
+	out = append(out, n)
+	for _, c := range n.children() {
+		out = c.descendants(out)
+	}
+	return out
+}
+
+// step is one step of a query.
+type step interface {
+	// apply appends the values selected below `n` to `out`.
+	apply(n node, out []node) []node
+}
+
+type childStep string
+
+func (s childStep) apply(n node, out []node) []node {
+	if table, ok := n.value.(map[string]interface{}); ok {
+		if v, ok := table[string(s)]; ok {
+			out = append(out, n.child(string(s), v))
+		}
+	}
+	return out
+}
+
+type wildcardStep struct{}
+
+func (wildcardStep) apply(n node, out []node) []node {
+	return append(out, n.children()...)
+}
+
+type indexStep int
+
+func (s indexStep) apply(n node, out []node) []node {
+	array, ok := n.value.([]interface{})
+	if !ok {
+		return out
+	}
+	i := int(s)
+	if i < 0 {
+		i += len(array)
+	}
+	if i < 0 || i >= len(array) {
+		return out
+	}
+	return append(out, n.child(strconv.Itoa(i), array[i]))
+}
+
+type sliceStep struct {
+	start, end       int
+	hasStart, hasEnd bool
+}
+
+func (s sliceStep) apply(n node, out []node) []node {
+	array, ok := n.value.([]interface{})
+	if !ok {
+		return out
+	}
+	start, end := 0, len(array)
+	if s.hasStart {
+		start = clamp(s.start, len(array))
+	}
+	if s.hasEnd {
+		end = clamp(s.end, len(array))
+	}
+	for i := start; i < end; i++ {
+		out = append(out, n.child(strconv.Itoa(i), array[i]))
+	}
+	return out
+}
+
+// clamp turns the slice bound `i`, which counts from the end if negative,
+// into an index between 0 and `n`.
+func clamp(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+type recursiveStep struct {
+	step step
+}
+
+func (s recursiveStep) apply(n node, out []node) []node {
+	for _, d := range n.descendants(nil) {
+		out = s.step.apply(d, out)
+	}
+	return out
+}
+
+type filterStep struct {
+	filter expr
+}
+
+func (s filterStep) apply(n node, out []node) []node {
+	for _, c := range n.children() {
+		if truthy(s.filter.eval(c.value)) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// typeOf returns the TOML type of a decoded value.
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case int64, *big.Int:
+		return "Integer"
+	case float64, *big.Float:
+		return "Float"
+	case string:
+		return "String"
+	case bool:
+		return "Bool"
+	case time.Time:
+		return "Datetime"
+	case []interface{}:
+		return "Array"
+	case map[string]interface{}:
+		return "Hash"
+	}
+	return reflect.TypeOf(v).String()
+}
@@ -0,0 +1,145 @@
+package query
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+var queryData = `
+title = "TOML Example"
+
+[owner]
+name = "Tom Preston-Werner"
+dob = 1979-05-27T07:32:00Z
+
+[database]
+server = "192.168.1.1"
+ports = [ 8001, 8001, 8002 ]
+connection_max = 5000
+enabled = true
+
+[servers.alpha]
+ip = "10.0.0.1"
+dc = "eqdc10"
+ports = [ 8080 ]
+
+[servers.beta]
+ip = "10.0.0.2"
+dc = "eqdc10"
+ports = [ 9090, 9091 ]
+enabled = false
+
+[servers.gamma]
+ip = "10.0.0.3"
+dc = "eqdc20"
+10.0.0.3 = "self"
+
+[clients]
+data = [ ["gamma", "delta"], [1, 2] ]
+`
+
+func decodeQueryData(t *testing.T) (map[string]interface{}, toml.MetaData) {
+	var data map[string]interface{}
+	md, err := toml.Decode(queryData, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, md
+}
+
+func TestExecute(t *testing.T) {
+	data, _ := decodeQueryData(t)
+
+	tests := map[string][]string{
+		"title":                    {"title"},
+		"$.owner.name":             {"owner.name"},
+		"servers.*.ip":             {"servers.alpha.ip", "servers.beta.ip", "servers.gamma.ip"},
+		"servers['alpha'].dc":      {"servers.alpha.dc"},
+		`servers.gamma."10.0.0.3"`: {"servers.gamma.10.0.0.3"},
+		"database.ports[0]":        {"database.ports.0"},
+		"database.ports[-1]":       {"database.ports.2"},
+		"database.ports[5]":        nil,
+		"database.ports[1:]":       {"database.ports.1", "database.ports.2"},
+		"database.ports[:-2]":      {"database.ports.0"},
+		"database.ports[*]":        {"database.ports.0", "database.ports.1", "database.ports.2"},
+		"clients.data[1][0]":       {"clients.data.1.0"},
+		"..ports[0]":               {"database.ports.0", "servers.alpha.ports.0", "servers.beta.ports.0"},
+		"$..dc":                    {"servers.alpha.dc", "servers.beta.dc", "servers.gamma.dc"},
+		"servers..*":               {"servers.alpha", "servers.beta", "servers.gamma", "servers.alpha.dc", "servers.alpha.ip", "servers.alpha.ports", "servers.alpha.ports.0", "servers.beta.dc", "servers.beta.enabled", "servers.beta.ip", "servers.beta.ports", "servers.beta.ports.0", "servers.beta.ports.1", "servers.gamma.10.0.0.3", "servers.gamma.dc", "servers.gamma.ip"},
+		"nope.*":                   nil,
+		"title[0]":                 nil,
+
+		`servers[?(@.dc == "eqdc10")].ip`:                                     {"servers.alpha.ip", "servers.beta.ip"},
+		`servers[?(@.dc != 'eqdc10')]`:                                        {"servers.gamma"},
+		`servers[?(@.ports[0] > 8080)].ip`:                                    {"servers.beta.ip"},
+		`servers[?(@.ports[0] >= 8080 && @.dc == "eqdc10")]`:                  {"servers.alpha", "servers.beta"},
+		`servers[?(@.ports || @.dc == "eqdc20")]`:                             {"servers.alpha", "servers.beta", "servers.gamma"},
+		`servers[?(@.enabled)]`:                                               nil,
+		`servers[?(@.enabled == false)]`:                                      {"servers.beta"},
+		`database.ports[?(@ < 8001.5)]`:                                       {"database.ports.0", "database.ports.1"},
+		`database.ports[?(@ == 8_002)]`:                                       {"database.ports.2"},
+		`$[?(@.dob < "1980-01-01T00:00:00Z")].name`:                           {"owner.name"},
+		`servers[?((@.dc == "eqdc20" || @.ip == "10.0.0.1") && @.ip != "x")]`: {"servers.alpha", "servers.gamma"},
+	}
+	for expr, want := range tests {
+		q, err := Compile(expr)
+		if err != nil {
+			t.Errorf("%s: %s", expr, err)
+			continue
+		}
+		var got []string
+		for _, m := range q.Execute(data) {
+			got = append(got, m.Key.String())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Expected\n%v\nbut got\n%v", expr, want, got)
+		}
+	}
+}
+
+func TestExecuteMeta(t *testing.T) {
+	data, md := decodeQueryData(t)
+
+	matches := MustCompile("database.*").ExecuteMeta(data, md)
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.Key.String()+" "+m.Type+" "+
+			strconv.Itoa(m.Position.Line))
+	}
+	want := "database.connection_max Integer 11, database.enabled Bool 12, " +
+		"database.ports Array 10, database.server String 9"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, strings.Join(got, ", "))
+	}
+
+	m := MustCompile("database.ports[2]").Execute(data)
+	if len(m) != 1 || m[0].Value != int64(8002) || m[0].Type != "Integer" {
+		t.Errorf("Unexpected match %#v", m)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                "Query '' is empty.",
+		"a.":              "Query 'a.': Expected a key but found the end at offset 2.",
+		"a[":              "Query 'a[': Expected an index at offset 2.",
+		"a[0":             "Query 'a[0': Expected ']' but found the end at offset 3.",
+		"a[x]":            "Query 'a[x]': Expected an index at offset 2.",
+		"a b":             "Query 'a b': Unexpected 'b' at offset 2.",
+		`a."b`:            `Query 'a."b': Unterminated string at offset 2.`,
+		`a."\q"`:          `Query 'a."\q"': Invalid string at offset 2.`,
+		"a[?(@.b ==)]":    "Query 'a[?(@.b ==)]': Expected a value but found ')' at offset 10.",
+		"a[?(@.b == 1]":   "Query 'a[?(@.b == 1]': Expected ')' but found ']' at offset 12.",
+		"a[?(@.b == 1x)]": "Query 'a[?(@.b == 1x)]': Expected ')' but found 'x' at offset 12.",
+	}
+	for expr, want := range tests {
+		_, err := Compile(expr)
+		if err == nil || err.Error() != want {
+			t.Errorf("%q: Expected %q but got %v", expr, want, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Secret is a string that is never shown: it formats as asterisks with the
+// verbs of package fmt, so that printing a configuration with `%v` or `%+v`
+// doesn't leak passwords and such. It decodes like a string, and is
+// redacted wherever the struct tag option `secret` redacts values. Convert
+// it to a string to get its value.
+type Secret string
+
+// redacted is shown in place of the values of secrets.
+const redacted = "********"
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// String returns asterisks rather than the secret.
+func (s Secret) String() string {
+	return redacted
+}
+
+// GoString returns asterisks rather than the secret, for the `%#v` verb.
+func (s Secret) GoString() string {
+	return redacted
+}
+
+// isSecretType returns true if values of type `rt` are secrets, or
+// pointers to secrets.
+func isSecretType(rt reflect.Type) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt == secretType
+}
+
+// isSecret returns true if the value of `key`, or a table it is in, was
+// decoded into a secret field.
+func isSecret(secrets map[string]bool, key Key) bool {
+	for i := len(key); i > 0; i-- {
+		if secrets[key[:i].String()] {
+			return true
+		}
+	}
+	return false
+}
+
+// redact removes the value `val` from the error message `msg`, where it may
+// appear in quotes or brackets as it was written or as decoded.
+func redact(msg string, val interface{}, lit Literal) string {
+	var texts []string
+	switch val := val.(type) {
+	case map[string]interface{}:
+		return msg
+	case []interface{}:
+		for i, v := range val {
+			var elem Literal
+			if i < len(lit.Elements) {
+				elem = lit.Elements[i]
+			}
+			msg = redact(msg, v, elem)
+		}
+	case string:
+		texts = append(texts, val)
+	}
+	texts = append(texts, lit.Text, literalOf(val).Text, fmt.Sprint(val))
+
+	pairs := make([]string, 0, 8*len(texts))
+	for _, text := range texts {
+		if len(text) == 0 {
+			continue
+		}
+		pairs = append(pairs,
+			"'"+text+"'", "'"+redacted+"'",
+			`"`+text+`"`, `"`+redacted+`"`,
+			fmt.Sprintf("%q", text), `"`+redacted+`"`,
+			"["+text+"]", "["+redacted+"]")
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+// valueAt returns the value of the key `key` in `mapping`.
+func valueAt(mapping map[string]interface{}, key Key) (interface{}, bool) {
+	var val interface{} = mapping
+	for _, k := range key {
+		table, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if val, ok = table[k]; !ok {
+			return nil, false
+		}
+	}
+	return val, true
+}
+
+// redactError returns `err` without the value of `key` in it, if the value
+// is secret.
+func (md *MetaData) redactError(key Key, err error) error {
+	if !isSecret(md.secrets, key) {
+		return err
+	}
+	val, ok := valueAt(md.mapping, key)
+	if !ok {
+		return err
+	}
+	msg := redact(err.Error(), val, literal(md.literals, key, val))
+	return e("%s", msg)
+}
+
+// Dump returns a description of every key in the TOML data, in the order
+// they were defined, with its type and value, to help with debugging. Values
+// are written as they were in the TOML data, except for values decoded into
+// a Secret or a field with the `secret` option, which are redacted:
+//
+//	database                      Hash
+//	    database.server    String    "192.168.1.1"
+//	    database.password  String    ********
+func (md MetaData) Dump() string {
+	return dump(md.Keys(), 0, func(key Key) (interface{}, tomlType) {
+		val, _ := valueAt(md.mapping, key)
+		return val, md.types[key.String()]
+	}, md.literals, md.secrets)
+}
+
+// dump returns a description of the keys `keys`, which are `depth` parts
+// longer than the keys they are shown as, for the Dump methods.
+func dump(keys []Key, depth int,
+	lookup func(key Key) (interface{}, tomlType),
+	literals map[string]Literal, secrets map[string]bool) string {
+
+	var buf bytes.Buffer
+	tabw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		val, typ := lookup(key)
+		if typ == nil {
+			typ = typeOfValue(val)
+		}
+		shown := key[depth:]
+
+		text := ""
+		switch {
+		case typ == tomlHash:
+		case isSecret(secrets, key):
+			text = redacted
+		default:
+			text = literal(literals, key, val).Text
+		}
+		fmt.Fprintf(tabw, "%s%s\t%s\t%s\n",
+			strings.Repeat("    ", len(shown)-1), shown, typ.typeString(),
+			text)
+	}
+	tabw.Flush()
+
+	// Tables have no value, so their lines end with the padding of the
+	// type column.
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, line := range lines {
+		if strings.HasSuffix(line, " \n") {
+			lines[i] = strings.TrimRight(line, " \n") + "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
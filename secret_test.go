@@ -0,0 +1,204 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var secretData = `
+user = "admin"
+password = "hunter2"
+
+[database]
+server = "192.168.1.1"
+token = "s3cr3t"
+pin = 1234
+`
+
+type secretConfig struct {
+	User     string
+	Password Secret
+	Database struct {
+		Server string
+		Token  string `toml:"token,secret"`
+		Pin    int    `toml:",secret"`
+	}
+}
+
+func TestSecretDecode(t *testing.T) {
+	var v secretConfig
+	if _, err := Decode(secretData, &v); err != nil {
+		t.Fatal(err)
+	}
+	if string(v.Password) != "hunter2" || v.Database.Token != "s3cr3t" {
+		t.Errorf("Expected secrets to decode as usual, but got %q and %q",
+			string(v.Password), v.Database.Token)
+	}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q"} {
+		if s := fmt.Sprintf(format, v); strings.Contains(s, "hunter2") {
+			t.Errorf("Expected %s to hide the password, but got %s",
+				format, s)
+		}
+	}
+}
+
+func TestSecretErrors(t *testing.T) {
+	var v struct {
+		Database struct {
+			Pin uint8 `toml:",secret"`
+		}
+		Password Secret `validate:"min=10,oneof=a b"`
+	}
+	_, err := Decode("password = 'hunter2'\n[database]\npin = 1234", &v)
+	if err == nil || strings.Contains(err.Error(), "1234") {
+		t.Errorf("Expected an error without the pin, but got %v", err)
+	}
+
+	var w struct {
+		Password Secret `validate:"oneof=a b"`
+	}
+	_, err = Decode("password = 'hunter2'", &w)
+	want := "Near line 1, key 'password': " +
+		"Value '********' is not one of 'a', 'b'."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+
+	hook := HookFunc(func(s string) (Secret, error) {
+		return "", e("'%s' is too short.", s)
+	})
+	dec := NewDecoder(strings.NewReader(`password = "hunter2"`))
+	dec.Hooks = []DecodeHook{hook}
+	_, err = dec.Decode(&w)
+	want = "Near line 1, key 'password': '********' is too short."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+}
+
+func TestSecretStrict(t *testing.T) {
+	var v struct {
+		PW struct{ X int } `toml:"pw,secret"`
+	}
+	err := CheckType(map[string]interface{}{"pw": "hunter2"}, v, nil)
+	want := "Key 'pw': Expected data to be a map: [********]"
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+
+	var w struct {
+		Pins     []int `toml:",secret"`
+		Password Secret
+	}
+	dec := NewDecoder(strings.NewReader("pins = '1234'\npassword = 'hunter2'"))
+	dec.Hooks = []DecodeHook{HookFunc(func(s string) (Secret, error) {
+		return "", e("'%s' is too short.", s)
+	})}
+	_, err = dec.DecodeStrict(&w, nil)
+	want = "Near line 1, key 'pins': Expected data to be an array: " +
+		"[********]\nNear line 2, key 'password': '********' is too short."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+}
+
+func TestSecretDump(t *testing.T) {
+	var v secretConfig
+	md, err := Decode(secretData, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `user                 String   "admin"
+password             String   ********
+database             Hash
+    database.server  String   "192.168.1.1"
+    database.token   String   ********
+    database.pin     Integer  ********
+`
+	if got := md.Dump(); got != want {
+		t.Errorf("Expected dump:\n%s\nbut got:\n%s", want, got)
+	}
+}
+
+func TestSecretTreeDump(t *testing.T) {
+	tree, err := LoadTree(secretData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Redact("password"); err != nil {
+		t.Fatal(err)
+	}
+	db := tree.Get("database").(*Tree)
+	if err := db.Redact("token"); err != nil {
+		t.Fatal(err)
+	}
+	want := `server  String   "192.168.1.1"
+token   String   ********
+pin     Integer  1234
+`
+	if got := db.Dump(); got != want {
+		t.Errorf("Expected dump:\n%s\nbut got:\n%s", want, got)
+	}
+	if got := tree.Dump(); !strings.Contains(got, "password  ") ||
+		strings.Contains(got, "hunter2") {
+
+		t.Errorf("Expected the password to be redacted, but got:\n%s", got)
+	}
+
+	// Values set after a secret is deleted aren't secret, but the secrets
+	// of other keys stay so.
+	if err := tree.Set("databases.token", "private"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Redact("databases.token"); err != nil {
+		t.Fatal(err)
+	}
+	tree.Delete("database")
+	if err := tree.Set("database.token", "public"); err != nil {
+		t.Fatal(err)
+	}
+	if got := tree.Dump(); !strings.Contains(got, `"public"`) ||
+		strings.Contains(got, "private") {
+
+		t.Errorf("Expected only the new token not to be redacted, but "+
+			"got:\n%s", got)
+	}
+}
+
+func TestSecretEncode(t *testing.T) {
+	var v secretConfig
+	if _, err := Decode(secretData, &v); err != nil {
+		t.Fatal(err)
+	}
+	var v2 struct {
+		User     string
+		Password Secret
+		Token    string `toml:"token,secret"`
+	}
+	v2.User, v2.Password, v2.Token = v.User, v.Password, v.Database.Token
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v2); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `Password = "hunter2"`) {
+		t.Errorf("Expected secrets to be encoded by default, but got:\n%s",
+			buf.String())
+	}
+
+	buf.Reset()
+	enc := NewEncoder(&buf)
+	enc.RedactSecrets = true
+	if err := enc.Encode(v2); err != nil {
+		t.Fatal(err)
+	}
+	want := `User = "admin"
+Password = "********"
+token = "********"
+`
+	if buf.String() != want {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", want, buf.String())
+	}
+}
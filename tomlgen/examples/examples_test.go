@@ -0,0 +1,259 @@
+package examples
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+type marshaler interface {
+	MarshalTOML() (interface{}, error)
+}
+
+var exampleFiles = []struct {
+	file     string
+	newValue func() interface{}
+	valid    bool
+}{
+	{"example.toml", func() interface{} { return new(tomlConfig) }, true},
+	{"hard.toml", func() interface{} { return new(hard) }, true},
+	{"implicit.toml", func() interface{} { return new(implicit) }, true},
+	{"readme1.toml", func() interface{} { return new(readme1) }, true},
+	{"readme2.toml", func() interface{} { return new(readme2) }, true},
+	{"config_test_multidecoder.toml",
+		func() interface{} { return new(map[string]multiDecoder) }, true},
+	{"invalid.toml", func() interface{} { return new(tomlConfig) }, false},
+	{"invalid-apples.toml",
+		func() interface{} { return new(map[string]interface{}) }, false},
+}
+
+func TestGeneratedMatchesDecode(t *testing.T) {
+	for _, ex := range exampleFiles {
+		fpath := filepath.Join("..", "..", "_examples", ex.file)
+
+		generated := ex.newValue()
+		genMD, genErr := toml.DecodeFile(fpath, generated)
+
+		reflected := ex.newValue()
+		dec := &toml.Decoder{IgnoreUnmarshalers: true}
+		reflMD, reflErr := dec.DecodeFile(fpath, reflected)
+
+		if ex.valid != (genErr == nil) || ex.valid != (reflErr == nil) {
+			t.Errorf("%s: Unexpected errors: generated %v, reflection %v",
+				ex.file, genErr, reflErr)
+			continue
+		}
+		if !ex.valid {
+			continue
+		}
+		if !reflect.DeepEqual(generated, reflected) {
+			t.Errorf("%s: Generated code decoded\n%#v\nbut reflection "+
+				"decoded\n%#v", ex.file, generated, reflected)
+		}
+		if !reflect.DeepEqual(genMD.Undecoded(), reflMD.Undecoded()) {
+			t.Errorf("%s: Undecoded keys differ: %v != %v", ex.file,
+				genMD.Undecoded(), reflMD.Undecoded())
+		}
+		if !sameWarnings(genMD.Warnings(), reflMD.Warnings()) {
+			t.Errorf("%s: Warnings differ: %v != %v", ex.file,
+				genMD.Warnings(), reflMD.Warnings())
+		}
+
+		// Encoding and decoding again gives the same value.
+		m, ok := generated.(marshaler)
+		if !ok {
+			continue
+		}
+		data, err := m.MarshalTOML()
+		if err != nil {
+			t.Errorf("%s: %s", ex.file, err)
+			continue
+		}
+		again := ex.newValue()
+		if err := again.(toml.Unmarshaler).UnmarshalTOML(data); err != nil {
+			t.Errorf("%s: %s", ex.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(again, generated) {
+			t.Errorf("%s: Encoding and decoding gave\n%#v\ninstead of\n%#v",
+				ex.file, again, generated)
+		}
+	}
+}
+
+var optionTests = []struct {
+	name     string
+	data     string
+	newValue func() interface{}
+	options  func(dec *toml.Decoder)
+}{
+	{"unknown keys", "Age = 1\ncolor = 'red'\n[extra]\nx = 1",
+		func() interface{} { return new(readme1) }, nil},
+	{"case folded keys", "AGE = 1\npi = 3.14\n[servers.alpha]\nip = 'a'",
+		func() interface{} { return new(tomlConfig) }, nil},
+	{"case folded keys", "AGE = 1\npi = 3.14",
+		func() interface{} { return new(readme1) }, nil},
+	{"empty interfaces", "[X.a.b]\nc = 1\n[X.a.b.d]\ne = 2",
+		func() interface{} { return new(implicit) }, nil},
+	{"anonymous structs", "[the]\ntest_string = 1",
+		func() interface{} { return new(hard) }, nil},
+	{"anonymous structs", "[the.hard]\ntest_array = ['a']\nfoo = 1",
+		func() interface{} { return new(hard) }, nil},
+	{"NoCaseFolding", "AGE = 1\nPi = 3.5",
+		func() interface{} { return new(readme1) },
+		func(dec *toml.Decoder) { dec.NoCaseFolding = true }},
+	{"CoerceNumbers", "Age = 1.0\nPi = 3",
+		func() interface{} { return new(readme1) },
+		func(dec *toml.Decoder) { dec.CoerceNumbers = true }},
+	{"BigNumbers", "Pi = 3.14159265358979323846264338327950288",
+		func() interface{} { return new(readme1) },
+		func(dec *toml.Decoder) { dec.BigNumbers = true }},
+	{"Hooks", "[servers.alpha]\nip = 'a'\ndc = 'eqdc10'",
+		func() interface{} { return new(tomlConfig) },
+		func(dec *toml.Decoder) {
+			dec.Hooks = []toml.DecodeHook{toml.HookFunc(
+				func(s string) (datacenter, error) {
+					return datacenter(strings.ToUpper(s)), nil
+				})}
+		}},
+	{"KeyMapper", "age = 1\nconnection_max = 2",
+		func() interface{} { return new(readme1) },
+		func(dec *toml.Decoder) { dec.KeyMapper = toml.SnakeCase }},
+	{"UseJSONTags", "Age = 1",
+		func() interface{} { return new(readme1) },
+		func(dec *toml.Decoder) { dec.UseJSONTags = true }},
+	{"PromoteWarnings", "Age = 1\ncolor = 'red'\npi = 3.5",
+		func() interface{} { return new(readme1) },
+		func(dec *toml.Decoder) {
+			dec.PromoteWarnings = []toml.WarningKind{toml.UnknownKey,
+				toml.CaseFoldedKey}
+		}},
+}
+
+func TestGeneratedMatchesOptions(t *testing.T) {
+	for _, test := range optionTests {
+		decode := func(ignore bool) (interface{}, toml.MetaData, error) {
+			v := test.newValue()
+			dec := toml.NewDecoder(strings.NewReader(test.data))
+			if test.options != nil {
+				test.options(dec)
+			}
+			dec.IgnoreUnmarshalers = ignore
+			md, err := dec.Decode(v)
+			return v, md, err
+		}
+		generated, genMD, genErr := decode(false)
+		reflected, reflMD, reflErr := decode(true)
+
+		if fmt.Sprint(genErr) != fmt.Sprint(reflErr) {
+			t.Errorf("%s: Generated code returned %v, but reflection %v",
+				test.name, genErr, reflErr)
+			continue
+		}
+		if !reflect.DeepEqual(generated, reflected) {
+			t.Errorf("%s: Generated code decoded\n%#v\nbut reflection "+
+				"decoded\n%#v", test.name, generated, reflected)
+		}
+		if !reflect.DeepEqual(genMD.Undecoded(), reflMD.Undecoded()) {
+			t.Errorf("%s: Undecoded keys differ: %v != %v", test.name,
+				genMD.Undecoded(), reflMD.Undecoded())
+		}
+		if !sameWarnings(genMD.Warnings(), reflMD.Warnings()) {
+			t.Errorf("%s: Warnings differ:\n%v\n%v", test.name,
+				genMD.Warnings(), reflMD.Warnings())
+		}
+	}
+}
+
+// TestGeneratedEncode encodes the values that generated code decodes with
+// an Encoder, which uses their MarshalTOML methods, and decodes the TOML
+// written again.
+func TestGeneratedEncode(t *testing.T) {
+	for _, ex := range exampleFiles {
+		if !ex.valid {
+			continue
+		}
+		fpath := filepath.Join("..", "..", "_examples", ex.file)
+		v := ex.newValue()
+		if _, err := toml.DecodeFile(fpath, v); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, ignore := range []bool{false, true} {
+			var buf bytes.Buffer
+			enc := toml.NewEncoder(&buf)
+			enc.IgnoreMarshalers = ignore
+			if err := enc.Encode(v); err != nil {
+				t.Errorf("%s: %s", ex.file, err)
+				continue
+			}
+			again := ex.newValue()
+			if _, err := toml.Decode(buf.String(), again); err != nil {
+				t.Errorf("%s: %s, decoding:\n%s", ex.file, err, buf.String())
+				continue
+			}
+			if !reflect.DeepEqual(v, again) {
+				t.Errorf("%s: Encoded\n%s\nwhich decodes to\n%#v\nrather "+
+					"than\n%#v", ex.file, buf.String(), again, v)
+			}
+		}
+	}
+}
+
+// sameWarnings returns true if `a` and `b` hold the same warnings, in any
+// order, since tables decoded into maps are visited in random order.
+func sameWarnings(a, b []toml.Warning) bool {
+	sorted := func(ws []toml.Warning) []string {
+		s := make([]string, len(ws))
+		for i, w := range ws {
+			s[i] = fmt.Sprintf("%d %s", w.Kind, w)
+		}
+		sort.Strings(s)
+		return s
+	}
+	return reflect.DeepEqual(sorted(a), sorted(b))
+}
+
+func TestGeneratedErrors(t *testing.T) {
+	var conf tomlConfig
+	_, err := toml.Decode("[database]\nports = [\"x\"]", &conf)
+	want := "Near line 2, key 'database.ports': Type mismatch for " +
+		"'examples.database.Ports': Expected integer but found 'string'."
+	if err == nil || err.Error() != want {
+		t.Fatalf("Expected %q but got %v", want, err)
+	}
+
+	_, err = toml.Decode("[servers.alpha]\nIp = \"a\"\nip = \"b\"", &conf)
+	want = "Near line 1, key 'servers.alpha': Cannot decode " +
+		"'examples.server.IP': Keys 'Ip', 'ip' all match 'IP' case " +
+		"insensitively."
+	if err == nil || err.Error() != want {
+		t.Fatalf("Expected %q but got %v", want, err)
+	}
+}
+
+func TestMarshalOmitEmpty(t *testing.T) {
+	conf := tomlConfig{Notes: "not encoded"}
+	data, err := conf.MarshalTOML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmap := data.(map[string]interface{})
+	if _, ok := tmap["Notes"]; ok {
+		t.Errorf("Expected skipped fields not to be encoded")
+	}
+	clients := tmap["Clients"].(map[string]interface{})
+	if _, ok := clients["Hosts"]; ok {
+		t.Errorf("Expected empty omitempty fields not to be encoded")
+	}
+	owner := tmap["Owner"].(map[string]interface{})
+	if _, ok := owner["DOB"]; ok {
+		t.Errorf("Expected nil pointers not to be encoded")
+	}
+}
@@ -0,0 +1,1258 @@
+// Code generated by tomlgen. DO NOT EDIT.
+
+package examples
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *tomlConfig) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *tomlConfig) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *tomlConfig) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("Title", "Title"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.Title, tmap[k]); err != nil {
+			return s.FieldError(k, "Title", err)
+		}
+	}
+	if k, ok, err := s.Field("Owner", "Owner"); err != nil {
+		return err
+	} else if ok {
+		if err := (&v.Owner).DecodeTOML(s.At(k), tmap[k]); err != nil {
+			return s.FieldError(k, "Owner", err)
+		}
+	}
+	if k, ok, err := s.Field("DB", "database"); err != nil {
+		return err
+	} else if ok {
+		if err := (&v.DB).DecodeTOML(s.At(k), tmap[k]); err != nil {
+			return s.FieldError(k, "DB", err)
+		}
+	}
+	if k, ok, err := s.Field("Servers", "Servers"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeMapServer(s.At(k), &v.Servers, tmap[k]); err != nil {
+			return s.FieldError(k, "Servers", err)
+		}
+	}
+	if k, ok, err := s.Field("Clients", "Clients"); err != nil {
+		return err
+	} else if ok {
+		if err := (&v.Clients).DecodeTOML(s.At(k), tmap[k]); err != nil {
+			return s.FieldError(k, "Clients", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *tomlConfig) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeString(&v.Title); err != nil {
+		return nil, tomlgenAt("Title", err)
+	} else if d != nil {
+		tmap["Title"] = d
+	}
+	if d, err := (&v.Owner).MarshalTOML(); err != nil {
+		return nil, tomlgenAt("Owner", err)
+	} else if d != nil {
+		tmap["Owner"] = d
+	}
+	if d, err := (&v.DB).MarshalTOML(); err != nil {
+		return nil, tomlgenAt("database", err)
+	} else if d != nil {
+		tmap["database"] = d
+	}
+	if d, err := tomlgenEncodeMapServer(&v.Servers); err != nil {
+		return nil, tomlgenAt("Servers", err)
+	} else if d != nil {
+		tmap["Servers"] = d
+	}
+	if d, err := (&v.Clients).MarshalTOML(); err != nil {
+		return nil, tomlgenAt("Clients", err)
+	} else if d != nil {
+		tmap["Clients"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *hard) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *hard) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *hard) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("The", "The"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeStruct(s.At(k), &v.The, tmap[k]); err != nil {
+			return s.FieldError(k, "The", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *hard) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeStruct(&v.The); err != nil {
+		return nil, tomlgenAt("The", err)
+	} else if d != nil {
+		tmap["The"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *implicit) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *implicit) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *implicit) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("X", "X"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeMapMapMapAny(s.At(k), &v.X, tmap[k]); err != nil {
+			return s.FieldError(k, "X", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *implicit) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeMapMapMapAny(&v.X); err != nil {
+		return nil, tomlgenAt("X", err)
+	} else if d != nil {
+		tmap["X"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *readme1) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *readme1) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *readme1) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("Age", "Age"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeInt8(s.At(k), &v.Age, tmap[k]); err != nil {
+			return s.FieldError(k, "Age", err)
+		}
+	}
+	if k, ok, err := s.Field("Cats", "Cats"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeSliceString(s.At(k), &v.Cats, tmap[k]); err != nil {
+			return s.FieldError(k, "Cats", err)
+		}
+	}
+	if k, ok, err := s.Field("Pi", "Pi"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeFloat32(s.At(k), &v.Pi, tmap[k]); err != nil {
+			return s.FieldError(k, "Pi", err)
+		}
+	}
+	if k, ok, err := s.Field("Perfection", "Perfection"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeSliceInt64(s.At(k), &v.Perfection, tmap[k]); err != nil {
+			return s.FieldError(k, "Perfection", err)
+		}
+	}
+	if k, ok, err := s.Field("DOB", "DOB"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeTime(s.At(k), &v.DOB, tmap[k]); err != nil {
+			return s.FieldError(k, "DOB", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *readme1) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeInt8(&v.Age); err != nil {
+		return nil, tomlgenAt("Age", err)
+	} else if d != nil {
+		tmap["Age"] = d
+	}
+	if d, err := tomlgenEncodeSliceString(&v.Cats); err != nil {
+		return nil, tomlgenAt("Cats", err)
+	} else if d != nil {
+		tmap["Cats"] = d
+	}
+	if d, err := tomlgenEncodeFloat32(&v.Pi); err != nil {
+		return nil, tomlgenAt("Pi", err)
+	} else if d != nil {
+		tmap["Pi"] = d
+	}
+	if d, err := tomlgenEncodeSliceInt64(&v.Perfection); err != nil {
+		return nil, tomlgenAt("Perfection", err)
+	} else if d != nil {
+		tmap["Perfection"] = d
+	}
+	if d, err := tomlgenEncodeTime(&v.DOB); err != nil {
+		return nil, tomlgenAt("DOB", err)
+	} else if d != nil {
+		tmap["DOB"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *readme2) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *readme2) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *readme2) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("SomeKeyName", "some_key_NAME"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.SomeKeyName, tmap[k]); err != nil {
+			return s.FieldError(k, "SomeKeyName", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *readme2) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeString(&v.SomeKeyName); err != nil {
+		return nil, tomlgenAt("some_key_NAME", err)
+	} else if d != nil {
+		tmap["some_key_NAME"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *multiDecoder) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *multiDecoder) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *multiDecoder) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("Type", "Type"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.Type, tmap[k]); err != nil {
+			return s.FieldError(k, "Type", err)
+		}
+	}
+	if k, ok, err := s.Field("Order", "Order"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeSliceString(s.At(k), &v.Order, tmap[k]); err != nil {
+			return s.FieldError(k, "Order", err)
+		}
+	}
+	if k, ok, err := s.Field("Delegates", "Delegates"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeMapDecoderConfig(s.At(k), &v.Delegates, tmap[k]); err != nil {
+			return s.FieldError(k, "Delegates", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *multiDecoder) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeString(&v.Type); err != nil {
+		return nil, tomlgenAt("Type", err)
+	} else if d != nil {
+		tmap["Type"] = d
+	}
+	if d, err := tomlgenEncodeSliceString(&v.Order); err != nil {
+		return nil, tomlgenAt("Order", err)
+	} else if d != nil {
+		tmap["Order"] = d
+	}
+	if d, err := tomlgenEncodeMapDecoderConfig(&v.Delegates); err != nil {
+		return nil, tomlgenAt("Delegates", err)
+	} else if d != nil {
+		tmap["Delegates"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *ownerInfo) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *ownerInfo) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *ownerInfo) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("Name", "Name"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.Name, tmap[k]); err != nil {
+			return s.FieldError(k, "Name", err)
+		}
+	}
+	if k, ok, err := s.Field("Org", "organization"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.Org, tmap[k]); err != nil {
+			return s.FieldError(k, "Org", err)
+		}
+	}
+	if k, ok, err := s.Field("Bio", "Bio"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.Bio, tmap[k]); err != nil {
+			return s.FieldError(k, "Bio", err)
+		}
+	}
+	if k, ok, err := s.Field("DOB", "DOB"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodePtrTime(s.At(k), &v.DOB, tmap[k]); err != nil {
+			return s.FieldError(k, "DOB", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *ownerInfo) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeString(&v.Name); err != nil {
+		return nil, tomlgenAt("Name", err)
+	} else if d != nil {
+		tmap["Name"] = d
+	}
+	if d, err := tomlgenEncodeString(&v.Org); err != nil {
+		return nil, tomlgenAt("organization", err)
+	} else if d != nil {
+		tmap["organization"] = d
+	}
+	if d, err := tomlgenEncodeString(&v.Bio); err != nil {
+		return nil, tomlgenAt("Bio", err)
+	} else if d != nil {
+		tmap["Bio"] = d
+	}
+	if d, err := tomlgenEncodePtrTime(&v.DOB); err != nil {
+		return nil, tomlgenAt("DOB", err)
+	} else if d != nil {
+		tmap["DOB"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *database) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *database) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *database) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("Server", "Server"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.Server, tmap[k]); err != nil {
+			return s.FieldError(k, "Server", err)
+		}
+	}
+	if k, ok, err := s.Field("Ports", "Ports"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeSlicePort(s.At(k), &v.Ports, tmap[k]); err != nil {
+			return s.FieldError(k, "Ports", err)
+		}
+	}
+	if k, ok, err := s.Field("ConnMax", "connection_max"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeInt(s.At(k), &v.ConnMax, tmap[k]); err != nil {
+			return s.FieldError(k, "ConnMax", err)
+		}
+	}
+	if k, ok, err := s.Field("Enabled", "Enabled"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeBool(s.At(k), &v.Enabled, tmap[k]); err != nil {
+			return s.FieldError(k, "Enabled", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *database) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeString(&v.Server); err != nil {
+		return nil, tomlgenAt("Server", err)
+	} else if d != nil {
+		tmap["Server"] = d
+	}
+	if d, err := tomlgenEncodeSlicePort(&v.Ports); err != nil {
+		return nil, tomlgenAt("Ports", err)
+	} else if d != nil {
+		tmap["Ports"] = d
+	}
+	if d, err := tomlgenEncodeInt(&v.ConnMax); err != nil {
+		return nil, tomlgenAt("connection_max", err)
+	} else if d != nil {
+		tmap["connection_max"] = d
+	}
+	if d, err := tomlgenEncodeBool(&v.Enabled); err != nil {
+		return nil, tomlgenAt("Enabled", err)
+	} else if d != nil {
+		tmap["Enabled"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *server) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *server) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *server) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("IP", "IP"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.IP, tmap[k]); err != nil {
+			return s.FieldError(k, "IP", err)
+		}
+	}
+	if k, ok, err := s.Field("DC", "DC"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), (*string)(&v.DC), tmap[k]); err != nil {
+			return s.FieldError(k, "DC", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *server) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeString(&v.IP); err != nil {
+		return nil, tomlgenAt("IP", err)
+	} else if d != nil {
+		tmap["IP"] = d
+	}
+	if d, err := tomlgenEncodeString((*string)(&v.DC)); err != nil {
+		return nil, tomlgenAt("DC", err)
+	} else if d != nil {
+		tmap["DC"] = d
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *clients) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *clients) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *clients) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("Data", "Data"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeSliceSliceAny(s.At(k), &v.Data, tmap[k]); err != nil {
+			return s.FieldError(k, "Data", err)
+		}
+	}
+	if k, ok, err := s.Field("Hosts", "Hosts"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeSliceString(s.At(k), &v.Hosts, tmap[k]); err != nil {
+			return s.FieldError(k, "Hosts", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *clients) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeSliceSliceAny(&v.Data); err != nil {
+		return nil, tomlgenAt("Data", err)
+	} else if d != nil {
+		tmap["Data"] = d
+	}
+	if len(v.Hosts) != 0 {
+		if d, err := tomlgenEncodeSliceString(&v.Hosts); err != nil {
+			return nil, tomlgenAt("Hosts", err)
+		} else if d != nil {
+			tmap["Hosts"] = d
+		}
+	}
+	return tmap, nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (v *decoderConfig) UnmarshalTOML(data interface{}) error {
+	return v.DecodeTOML(nil, data)
+}
+
+// TOMLNaming implements toml.GeneratedUnmarshaler.
+func (v *decoderConfig) TOMLNaming() (string, bool) {
+	return "", false
+}
+
+// DecodeTOML implements toml.GeneratedUnmarshaler.
+func (v *decoderConfig) DecodeTOML(r *toml.Report, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("Type", "Type"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.Type, tmap[k]); err != nil {
+			return s.FieldError(k, "Type", err)
+		}
+	}
+	if k, ok, err := s.Field("EncodingName", "encoding_name"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodePtrString(s.At(k), &v.EncodingName, tmap[k]); err != nil {
+			return s.FieldError(k, "EncodingName", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+// MarshalTOML returns v as TOML data, in the form that UnmarshalTOML takes.
+func (v *decoderConfig) MarshalTOML() (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeString(&v.Type); err != nil {
+		return nil, tomlgenAt("Type", err)
+	} else if d != nil {
+		tmap["Type"] = d
+	}
+	if d, err := tomlgenEncodePtrString(&v.EncodingName); err != nil {
+		return nil, tomlgenAt("encoding_name", err)
+	} else if d != nil {
+		tmap["encoding_name"] = d
+	}
+	return tmap, nil
+}
+
+func tomlgenDecodeString(r *toml.Report, v *string, data interface{}) error {
+	d, ok := data.(string)
+	if !ok {
+		return fmt.Errorf("Expected string but found '%T'.", data)
+	}
+	*v = d
+	return nil
+}
+
+func tomlgenEncodeString(v *string) (interface{}, error) {
+	return *v, nil
+}
+
+func tomlgenDecodeMapServer(r *toml.Report, v *map[string]server, data interface{}) error {
+	tmap, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Expected map but found '%T'.", data)
+	}
+	if *v == nil {
+		*v = make(map[string]server, len(tmap))
+	}
+	for k, d := range tmap {
+		var e server
+		if err := (&e).DecodeTOML(r.Entry(k), d); err != nil {
+			return tomlgenAt(k, err)
+		}
+		(*v)[string(k)] = e
+	}
+	return nil
+}
+
+func tomlgenEncodeMapServer(v *map[string]server) (interface{}, error) {
+	tmap := make(map[string]interface{}, len(*v))
+	for k := range *v {
+		e := (*v)[k]
+		d, err := (&e).MarshalTOML()
+		if err != nil {
+			return nil, tomlgenAt(string(k), err)
+		}
+		if d != nil {
+			tmap[string(k)] = d
+		}
+	}
+	return tmap, nil
+}
+
+func tomlgenDecodeSliceString(r *toml.Report, v *[]string, data interface{}) error {
+	slice, ok := data.([]interface{})
+	if !ok {
+		return fmt.Errorf("Expected slice but found '%T'.", data)
+	}
+	*v = make([]string, len(slice))
+	for i, d := range slice {
+		if err := tomlgenDecodeString(r, &(*v)[i], d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tomlgenEncodeSliceString(v *[]string) (interface{}, error) {
+	arr := make([]interface{}, 0, len(*v))
+	for i := range *v {
+		d, err := tomlgenEncodeString(&(*v)[i])
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			arr = append(arr, d)
+		}
+	}
+	return arr, nil
+}
+
+func tomlgenDecodeAny(r *toml.Report, v *interface{}, data interface{}) error {
+	*v = data
+	r.Decoded(data)
+	return nil
+}
+
+func tomlgenEncodeAny(v *interface{}) (interface{}, error) {
+	return *v, nil
+}
+
+func tomlgenDecodeMapAny(r *toml.Report, v *map[string]interface{}, data interface{}) error {
+	tmap, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Expected map but found '%T'.", data)
+	}
+	if *v == nil {
+		*v = make(map[string]interface{}, len(tmap))
+	}
+	for k, d := range tmap {
+		var e interface{}
+		if err := tomlgenDecodeAny(r.Entry(k), &e, d); err != nil {
+			return tomlgenAt(k, err)
+		}
+		(*v)[string(k)] = e
+	}
+	return nil
+}
+
+func tomlgenEncodeMapAny(v *map[string]interface{}) (interface{}, error) {
+	tmap := make(map[string]interface{}, len(*v))
+	for k := range *v {
+		e := (*v)[k]
+		d, err := tomlgenEncodeAny(&e)
+		if err != nil {
+			return nil, tomlgenAt(string(k), err)
+		}
+		if d != nil {
+			tmap[string(k)] = d
+		}
+	}
+	return tmap, nil
+}
+
+func tomlgenDecodeStruct2(r *toml.Report, v *struct {
+	TestArray         []string               `toml:"test_array"`
+	TestArray2        []string               `toml:"test_array2"`
+	AnotherTestString string                 `toml:"another_test_string"`
+	HarderTestString  string                 `toml:"harder_test_string"`
+	Bit               map[string]interface{} `toml:"bit#"`
+}, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("TestArray", "test_array"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeSliceString(s.At(k), &v.TestArray, tmap[k]); err != nil {
+			return s.FieldError(k, "TestArray", err)
+		}
+	}
+	if k, ok, err := s.Field("TestArray2", "test_array2"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeSliceString(s.At(k), &v.TestArray2, tmap[k]); err != nil {
+			return s.FieldError(k, "TestArray2", err)
+		}
+	}
+	if k, ok, err := s.Field("AnotherTestString", "another_test_string"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.AnotherTestString, tmap[k]); err != nil {
+			return s.FieldError(k, "AnotherTestString", err)
+		}
+	}
+	if k, ok, err := s.Field("HarderTestString", "harder_test_string"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.HarderTestString, tmap[k]); err != nil {
+			return s.FieldError(k, "HarderTestString", err)
+		}
+	}
+	if k, ok, err := s.Field("Bit", "bit#"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeMapAny(s.At(k), &v.Bit, tmap[k]); err != nil {
+			return s.FieldError(k, "Bit", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+func tomlgenEncodeStruct2(v *struct {
+	TestArray         []string               `toml:"test_array"`
+	TestArray2        []string               `toml:"test_array2"`
+	AnotherTestString string                 `toml:"another_test_string"`
+	HarderTestString  string                 `toml:"harder_test_string"`
+	Bit               map[string]interface{} `toml:"bit#"`
+}) (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeSliceString(&v.TestArray); err != nil {
+		return nil, tomlgenAt("test_array", err)
+	} else if d != nil {
+		tmap["test_array"] = d
+	}
+	if d, err := tomlgenEncodeSliceString(&v.TestArray2); err != nil {
+		return nil, tomlgenAt("test_array2", err)
+	} else if d != nil {
+		tmap["test_array2"] = d
+	}
+	if d, err := tomlgenEncodeString(&v.AnotherTestString); err != nil {
+		return nil, tomlgenAt("another_test_string", err)
+	} else if d != nil {
+		tmap["another_test_string"] = d
+	}
+	if d, err := tomlgenEncodeString(&v.HarderTestString); err != nil {
+		return nil, tomlgenAt("harder_test_string", err)
+	} else if d != nil {
+		tmap["harder_test_string"] = d
+	}
+	if d, err := tomlgenEncodeMapAny(&v.Bit); err != nil {
+		return nil, tomlgenAt("bit#", err)
+	} else if d != nil {
+		tmap["bit#"] = d
+	}
+	return tmap, nil
+}
+
+func tomlgenDecodeStruct(r *toml.Report, v *struct {
+	TestString string `toml:"test_string"`
+	Hard       struct {
+		TestArray         []string               `toml:"test_array"`
+		TestArray2        []string               `toml:"test_array2"`
+		AnotherTestString string                 `toml:"another_test_string"`
+		HarderTestString  string                 `toml:"harder_test_string"`
+		Bit               map[string]interface{} `toml:"bit#"`
+	}
+}, data interface{}) error {
+	s, tmap, err := r.Struct(data, v)
+	if err != nil {
+		return err
+	}
+	if k, ok, err := s.Field("TestString", "test_string"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeString(s.At(k), &v.TestString, tmap[k]); err != nil {
+			return s.FieldError(k, "TestString", err)
+		}
+	}
+	if k, ok, err := s.Field("Hard", "Hard"); err != nil {
+		return err
+	} else if ok {
+		if err := tomlgenDecodeStruct2(s.At(k), &v.Hard, tmap[k]); err != nil {
+			return s.FieldError(k, "Hard", err)
+		}
+	}
+	s.Done()
+	return nil
+}
+
+func tomlgenEncodeStruct(v *struct {
+	TestString string `toml:"test_string"`
+	Hard       struct {
+		TestArray         []string               `toml:"test_array"`
+		TestArray2        []string               `toml:"test_array2"`
+		AnotherTestString string                 `toml:"another_test_string"`
+		HarderTestString  string                 `toml:"harder_test_string"`
+		Bit               map[string]interface{} `toml:"bit#"`
+	}
+}) (interface{}, error) {
+	tmap := make(map[string]interface{})
+	if d, err := tomlgenEncodeString(&v.TestString); err != nil {
+		return nil, tomlgenAt("test_string", err)
+	} else if d != nil {
+		tmap["test_string"] = d
+	}
+	if d, err := tomlgenEncodeStruct2(&v.Hard); err != nil {
+		return nil, tomlgenAt("Hard", err)
+	} else if d != nil {
+		tmap["Hard"] = d
+	}
+	return tmap, nil
+}
+
+func tomlgenDecodeMapMapAny(r *toml.Report, v *map[string]map[string]interface{}, data interface{}) error {
+	tmap, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Expected map but found '%T'.", data)
+	}
+	if *v == nil {
+		*v = make(map[string]map[string]interface{}, len(tmap))
+	}
+	for k, d := range tmap {
+		var e map[string]interface{}
+		if err := tomlgenDecodeMapAny(r.Entry(k), &e, d); err != nil {
+			return tomlgenAt(k, err)
+		}
+		(*v)[string(k)] = e
+	}
+	return nil
+}
+
+func tomlgenEncodeMapMapAny(v *map[string]map[string]interface{}) (interface{}, error) {
+	tmap := make(map[string]interface{}, len(*v))
+	for k := range *v {
+		e := (*v)[k]
+		d, err := tomlgenEncodeMapAny(&e)
+		if err != nil {
+			return nil, tomlgenAt(string(k), err)
+		}
+		if d != nil {
+			tmap[string(k)] = d
+		}
+	}
+	return tmap, nil
+}
+
+func tomlgenDecodeMapMapMapAny(r *toml.Report, v *map[string]map[string]map[string]interface{}, data interface{}) error {
+	tmap, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Expected map but found '%T'.", data)
+	}
+	if *v == nil {
+		*v = make(map[string]map[string]map[string]interface{}, len(tmap))
+	}
+	for k, d := range tmap {
+		var e map[string]map[string]interface{}
+		if err := tomlgenDecodeMapMapAny(r.Entry(k), &e, d); err != nil {
+			return tomlgenAt(k, err)
+		}
+		(*v)[string(k)] = e
+	}
+	return nil
+}
+
+func tomlgenEncodeMapMapMapAny(v *map[string]map[string]map[string]interface{}) (interface{}, error) {
+	tmap := make(map[string]interface{}, len(*v))
+	for k := range *v {
+		e := (*v)[k]
+		d, err := tomlgenEncodeMapMapAny(&e)
+		if err != nil {
+			return nil, tomlgenAt(string(k), err)
+		}
+		if d != nil {
+			tmap[string(k)] = d
+		}
+	}
+	return tmap, nil
+}
+
+func tomlgenDecodeInt8(r *toml.Report, v *int8, data interface{}) error {
+	d, ok := data.(int64)
+	if !ok {
+		return fmt.Errorf("Expected integer but found '%T'.", data)
+	}
+	*v = int8(d)
+	return nil
+}
+
+func tomlgenEncodeInt8(v *int8) (interface{}, error) {
+	return int64(*v), nil
+}
+
+func tomlgenDecodeFloat32(r *toml.Report, v *float32, data interface{}) error {
+	d, ok := data.(float64)
+	if !ok {
+		return fmt.Errorf("Expected float but found '%T'.", data)
+	}
+	*v = r.Float32(d)
+	return nil
+}
+
+func tomlgenEncodeFloat32(v *float32) (interface{}, error) {
+	return float64(*v), nil
+}
+
+func tomlgenDecodeInt64(r *toml.Report, v *int64, data interface{}) error {
+	d, ok := data.(int64)
+	if !ok {
+		return fmt.Errorf("Expected integer but found '%T'.", data)
+	}
+	*v = d
+	return nil
+}
+
+func tomlgenEncodeInt64(v *int64) (interface{}, error) {
+	return int64(*v), nil
+}
+
+func tomlgenDecodeSliceInt64(r *toml.Report, v *[]int64, data interface{}) error {
+	slice, ok := data.([]interface{})
+	if !ok {
+		return fmt.Errorf("Expected slice but found '%T'.", data)
+	}
+	*v = make([]int64, len(slice))
+	for i, d := range slice {
+		if err := tomlgenDecodeInt64(r, &(*v)[i], d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tomlgenEncodeSliceInt64(v *[]int64) (interface{}, error) {
+	arr := make([]interface{}, 0, len(*v))
+	for i := range *v {
+		d, err := tomlgenEncodeInt64(&(*v)[i])
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			arr = append(arr, d)
+		}
+	}
+	return arr, nil
+}
+
+func tomlgenDecodeTime(r *toml.Report, v *time.Time, data interface{}) error {
+	d, ok := data.(time.Time)
+	if !ok {
+		return fmt.Errorf("Expected time.Time but found '%T'.", data)
+	}
+	*v = d
+	return nil
+}
+
+func tomlgenEncodeTime(v *time.Time) (interface{}, error) {
+	return *v, nil
+}
+
+func tomlgenDecodeMapDecoderConfig(r *toml.Report, v *map[string]decoderConfig, data interface{}) error {
+	tmap, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Expected map but found '%T'.", data)
+	}
+	if *v == nil {
+		*v = make(map[string]decoderConfig, len(tmap))
+	}
+	for k, d := range tmap {
+		var e decoderConfig
+		if err := (&e).DecodeTOML(r.Entry(k), d); err != nil {
+			return tomlgenAt(k, err)
+		}
+		(*v)[string(k)] = e
+	}
+	return nil
+}
+
+func tomlgenEncodeMapDecoderConfig(v *map[string]decoderConfig) (interface{}, error) {
+	tmap := make(map[string]interface{}, len(*v))
+	for k := range *v {
+		e := (*v)[k]
+		d, err := (&e).MarshalTOML()
+		if err != nil {
+			return nil, tomlgenAt(string(k), err)
+		}
+		if d != nil {
+			tmap[string(k)] = d
+		}
+	}
+	return tmap, nil
+}
+
+func tomlgenDecodePtrTime(r *toml.Report, v **time.Time, data interface{}) error {
+	if *v == nil {
+		*v = new(time.Time)
+	}
+	return tomlgenDecodeTime(r, *v, data)
+}
+
+func tomlgenEncodePtrTime(v **time.Time) (interface{}, error) {
+	if *v == nil {
+		return nil, nil
+	}
+	return tomlgenEncodeTime(*v)
+}
+
+func tomlgenDecodeUint16(r *toml.Report, v *uint16, data interface{}) error {
+	d, ok := data.(int64)
+	if !ok {
+		return fmt.Errorf("Expected integer but found '%T'.", data)
+	}
+	*v = uint16(d)
+	return nil
+}
+
+func tomlgenEncodeUint16(v *uint16) (interface{}, error) {
+	return int64(*v), nil
+}
+
+func tomlgenDecodeSlicePort(r *toml.Report, v *[]port, data interface{}) error {
+	slice, ok := data.([]interface{})
+	if !ok {
+		return fmt.Errorf("Expected slice but found '%T'.", data)
+	}
+	*v = make([]port, len(slice))
+	for i, d := range slice {
+		if err := tomlgenDecodeUint16(r, (*uint16)(&(*v)[i]), d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tomlgenEncodeSlicePort(v *[]port) (interface{}, error) {
+	arr := make([]interface{}, 0, len(*v))
+	for i := range *v {
+		d, err := tomlgenEncodeUint16((*uint16)(&(*v)[i]))
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			arr = append(arr, d)
+		}
+	}
+	return arr, nil
+}
+
+func tomlgenDecodeInt(r *toml.Report, v *int, data interface{}) error {
+	d, ok := data.(int64)
+	if !ok {
+		return fmt.Errorf("Expected integer but found '%T'.", data)
+	}
+	*v = int(d)
+	return nil
+}
+
+func tomlgenEncodeInt(v *int) (interface{}, error) {
+	return int64(*v), nil
+}
+
+func tomlgenDecodeBool(r *toml.Report, v *bool, data interface{}) error {
+	d, ok := data.(bool)
+	if !ok {
+		return fmt.Errorf("Expected bool but found '%T'.", data)
+	}
+	*v = d
+	return nil
+}
+
+func tomlgenEncodeBool(v *bool) (interface{}, error) {
+	return *v, nil
+}
+
+func tomlgenDecodeSliceAny(r *toml.Report, v *[]interface{}, data interface{}) error {
+	slice, ok := data.([]interface{})
+	if !ok {
+		return fmt.Errorf("Expected slice but found '%T'.", data)
+	}
+	*v = make([]interface{}, len(slice))
+	for i, d := range slice {
+		if err := tomlgenDecodeAny(r, &(*v)[i], d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tomlgenEncodeSliceAny(v *[]interface{}) (interface{}, error) {
+	arr := make([]interface{}, 0, len(*v))
+	for i := range *v {
+		d, err := tomlgenEncodeAny(&(*v)[i])
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			arr = append(arr, d)
+		}
+	}
+	return arr, nil
+}
+
+func tomlgenDecodeSliceSliceAny(r *toml.Report, v *[][]interface{}, data interface{}) error {
+	slice, ok := data.([]interface{})
+	if !ok {
+		return fmt.Errorf("Expected slice but found '%T'.", data)
+	}
+	*v = make([][]interface{}, len(slice))
+	for i, d := range slice {
+		if err := tomlgenDecodeSliceAny(r, &(*v)[i], d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tomlgenEncodeSliceSliceAny(v *[][]interface{}) (interface{}, error) {
+	arr := make([]interface{}, 0, len(*v))
+	for i := range *v {
+		d, err := tomlgenEncodeSliceAny(&(*v)[i])
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			arr = append(arr, d)
+		}
+	}
+	return arr, nil
+}
+
+func tomlgenDecodePtrString(r *toml.Report, v **string, data interface{}) error {
+	if *v == nil {
+		*v = new(string)
+	}
+	return tomlgenDecodeString(r, *v, data)
+}
+
+func tomlgenEncodePtrString(v **string) (interface{}, error) {
+	if *v == nil {
+		return nil, nil
+	}
+	return tomlgenEncodeString(*v)
+}
+
+// tomlgenAt returns err, which occurred at the key k, as a
+// *toml.DecodeError. Errors that already are one occurred deeper, so k is
+// prepended to their keys.
+func tomlgenAt(k string, err error) error {
+	if de, ok := err.(*toml.DecodeError); ok {
+		return &toml.DecodeError{Key: append(toml.Key{k}, de.Key...),
+			Err: de.Err}
+	}
+	return &toml.DecodeError{Key: toml.Key{k}, Err: err}
+}
@@ -0,0 +1,100 @@
+// Package examples holds Go types for the TOML files in `_examples`, along
+// with the code that tomlgen generates for them. Its tests check that the
+// generated code decodes the files exactly like the reflection based
+// decoder does.
+package examples
+
+//go:generate go run .. -type tomlConfig,hard,implicit,readme1,readme2,multiDecoder .
+
+import (
+	"time"
+)
+
+// Types for example.toml.
+
+type tomlConfig struct {
+	Title   string
+	Owner   ownerInfo
+	DB      database `toml:"database"`
+	Servers map[string]server
+	Clients clients
+	Notes   string `toml:"-"`
+}
+
+type ownerInfo struct {
+	Name string
+	Org  string `toml:"organization"`
+	Bio  string
+	DOB  *time.Time
+}
+
+type database struct {
+	Server  string
+	Ports   []port
+	ConnMax int `toml:"connection_max"`
+	Enabled bool
+}
+
+type port uint16
+
+type server struct {
+	IP string
+	DC datacenter
+}
+
+type datacenter string
+
+type clients struct {
+	Data  [][]interface{}
+	Hosts []string `toml:",omitempty"`
+}
+
+// Types for hard.toml.
+
+type hard struct {
+	The struct {
+		TestString string `toml:"test_string"`
+		Hard       struct {
+			TestArray         []string               `toml:"test_array"`
+			TestArray2        []string               `toml:"test_array2"`
+			AnotherTestString string                 `toml:"another_test_string"`
+			HarderTestString  string                 `toml:"harder_test_string"`
+			Bit               map[string]interface{} `toml:"bit#"`
+		}
+	}
+}
+
+// Types for implicit.toml.
+
+type implicit struct {
+	X map[string]map[string]map[string]interface{}
+}
+
+// Types for readme1.toml.
+
+type readme1 struct {
+	Age        int8
+	Cats       []string
+	Pi         float32
+	Perfection []int64
+	DOB        time.Time
+}
+
+// Types for readme2.toml.
+
+type readme2 struct {
+	SomeKeyName string `toml:"some_key_NAME"`
+}
+
+// Types for config_test_multidecoder.toml.
+
+type multiDecoder struct {
+	Type      string
+	Order     []string
+	Delegates map[string]decoderConfig
+}
+
+type decoderConfig struct {
+	Type         string
+	EncodingName *string `toml:"encoding_name"`
+}
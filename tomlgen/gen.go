@@ -0,0 +1,728 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const tomlPath = "github.com/BurntSushi/toml"
+
+// generator writes the decoding and encoding code for the struct types of
+// one package.
+type generator struct {
+	fset *token.FileSet
+	pkg  string
+
+	// the named types of the package, in the order they are declared
+	specs []*ast.TypeSpec
+	named map[string]*ast.TypeSpec
+
+	// the methods of each named type, by name
+	methods map[string]map[string]bool
+
+	keys    string
+	mapper  toml.KeyMapper
+	useJSON bool
+
+	// the named struct types to generate methods for, and those done
+	queue []string
+	done  map[string]bool
+
+	// the names of the helper functions for each type, by type expression,
+	// and the type expression each name is used for
+	helpers map[string]string
+	taken   map[string]string
+
+	methodsOut bytes.Buffer
+	helpersOut bytes.Buffer
+	usesTime   bool
+}
+
+// kind is the way a Go type is decoded and encoded.
+type kind int
+
+const (
+	kindBasic  kind = iota // a predeclared string, bool or number type
+	kindTime               // time.Time
+	kindAny                // an empty interface
+	kindMethod             // a type with UnmarshalTOML and MarshalTOML
+	kindGen                // a named struct type with generated methods
+	kindConv               // a named type converted to its underlying type
+	kindStruct             // an anonymous struct
+	kindPtr
+	kindSlice
+	kindMap
+)
+
+// gotype describes a Go type found in the source of the package.
+type gotype struct {
+	kind kind
+
+	// the Go source of the type
+	expr string
+
+	// for basic types, one of "string", "bool", "int", "uint" or "float"
+	class string
+
+	// the element type of pointers, slices and maps, and the underlying
+	// type of kindConv types
+	elem *gotype
+
+	// the key type of maps
+	key *gotype
+
+	// the fields of anonymous structs
+	fields []field
+}
+
+var basicClasses = map[string]string{
+	"string": "string", "bool": "bool",
+	"int": "int", "int8": "int", "int16": "int", "int32": "int",
+	"int64": "int", "rune": "int",
+	"uint": "uint", "uint8": "uint", "uint16": "uint", "uint32": "uint",
+	"uint64": "uint", "byte": "uint",
+	"float32": "float", "float64": "float",
+}
+
+var keyMappers = map[string]toml.KeyMapper{
+	"snake":      toml.SnakeCase,
+	"kebab":      toml.KebabCase,
+	"lowercamel": toml.LowerCamelCase,
+}
+
+// newGenerator parses the Go package in `dir`, except for its tests and the
+// file `output` that the code will be written to.
+func newGenerator(
+	dir, output, keys string, useJSON bool) (*generator, error) {
+
+	g := &generator{
+		fset:    token.NewFileSet(),
+		named:   make(map[string]*ast.TypeSpec),
+		methods: make(map[string]map[string]bool),
+		keys:    keys,
+		useJSON: useJSON,
+		done:    make(map[string]bool),
+		helpers: make(map[string]string),
+		taken:   make(map[string]string),
+	}
+	if len(keys) > 0 {
+		if g.mapper = keyMappers[keys]; g.mapper == nil {
+			return nil, fmt.Errorf("Unknown key mapper '%s'.", keys)
+		}
+	}
+
+	outInfo, _ := os.Stat(output)
+	skip := func(info os.FileInfo) bool {
+		if strings.HasSuffix(info.Name(), "_test.go") {
+			return false
+		}
+		return outInfo == nil || !os.SameFile(info, outInfo)
+	}
+	pkgs, err := parser.ParseDir(g.fset, dir, skip, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("Expected one package in '%s' but found %d.",
+			dir, len(pkgs))
+	}
+	for name, pkg := range pkgs {
+		g.pkg = name
+		fnames := make([]string, 0, len(pkg.Files))
+		for fname := range pkg.Files {
+			fnames = append(fnames, fname)
+		}
+		sort.Strings(fnames)
+		for _, fname := range fnames {
+			g.collect(pkg.Files[fname])
+		}
+	}
+	return g, nil
+}
+
+// collect records the named types and methods declared in `f`.
+func (g *generator) collect(f *ast.File) {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				g.specs = append(g.specs, spec)
+				g.named[spec.Name.Name] = spec
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) != 1 {
+				continue
+			}
+			recv := decl.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); ok {
+				if g.methods[ident.Name] == nil {
+					g.methods[ident.Name] = make(map[string]bool)
+				}
+				g.methods[ident.Name][decl.Name.Name] = true
+			}
+		}
+	}
+}
+
+// generate returns the formatted source of the code for the struct types
+// named `types`, or for every struct type of the package if there are none.
+func (g *generator) generate(types []string) ([]byte, error) {
+	if len(types) == 0 {
+		for _, spec := range g.specs {
+			if _, ok := spec.Type.(*ast.StructType); ok {
+				types = append(types, spec.Name.Name)
+			}
+		}
+	}
+	for _, name := range types {
+		spec, ok := g.named[name]
+		if !ok {
+			return nil, fmt.Errorf("Type '%s' is not declared in package "+
+				"'%s'.", name, g.pkg)
+		}
+		if _, ok := spec.Type.(*ast.StructType); !ok {
+			return nil, fmt.Errorf("Type '%s' is not a struct type.", name)
+		}
+		if err := g.enqueue(name); err != nil {
+			return nil, err
+		}
+	}
+	for len(g.queue) > 0 {
+		name := g.queue[0]
+		g.queue = g.queue[1:]
+		if err := g.structMethods(name); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by tomlgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", g.pkg)
+	fmt.Fprintf(&out, "\t\"fmt\"\n")
+	if g.usesTime {
+		fmt.Fprintf(&out, "\t\"time\"\n")
+	}
+	fmt.Fprintf(&out, "\n\t%q\n)\n", tomlPath)
+	out.Write(g.methodsOut.Bytes())
+	out.Write(g.helpersOut.Bytes())
+	out.WriteString(runtime)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("BUG: generated invalid code: %s", err)
+	}
+	return src, nil
+}
+
+// enqueue adds the named struct type `name` to the types to generate
+// methods for, unless it has them already.
+func (g *generator) enqueue(name string) error {
+	if g.done[name] {
+		return nil
+	}
+	if g.methods[name]["UnmarshalTOML"] || g.methods[name]["MarshalTOML"] {
+		return fmt.Errorf("Type '%s' already has an UnmarshalTOML or "+
+			"MarshalTOML method.", name)
+	}
+	g.done[name] = true
+	g.queue = append(g.queue, name)
+	return nil
+}
+
+// resolve describes the type `expr` found in the source.
+func (g *generator) resolve(expr ast.Expr) (*gotype, error) {
+	t := &gotype{expr: g.source(expr)}
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return g.resolve(expr.X)
+	case *ast.Ident:
+		if class, ok := basicClasses[expr.Name]; ok {
+			t.kind, t.class = kindBasic, class
+			return t, nil
+		}
+		if expr.Name == "any" {
+			t.kind = kindAny
+			return t, nil
+		}
+		spec, ok := g.named[expr.Name]
+		if !ok || spec.TypeParams != nil {
+			break
+		}
+		methods := g.methods[expr.Name]
+		switch {
+		case methods["UnmarshalTOML"] && methods["MarshalTOML"]:
+			t.kind = kindMethod
+			return t, nil
+		case methods["UnmarshalTOML"] || methods["MarshalTOML"]:
+			return nil, fmt.Errorf("Type '%s' must have both an "+
+				"UnmarshalTOML and a MarshalTOML method, or neither.",
+				expr.Name)
+		case spec.Assign.IsValid():
+			return g.resolve(spec.Type)
+		}
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			t.kind = kindGen
+			return t, g.enqueue(expr.Name)
+		}
+		elem, err := g.resolve(spec.Type)
+		if err != nil {
+			return nil, err
+		}
+		t.kind, t.elem = kindConv, elem
+		return t, nil
+	case *ast.SelectorExpr:
+		if pkg, ok := expr.X.(*ast.Ident); ok &&
+			pkg.Name == "time" && expr.Sel.Name == "Time" {
+
+			g.usesTime = true
+			t.kind = kindTime
+			return t, nil
+		}
+	case *ast.StarExpr:
+		elem, err := g.resolve(expr.X)
+		if err != nil {
+			return nil, err
+		}
+		t.kind, t.elem = kindPtr, elem
+		return t, nil
+	case *ast.ArrayType:
+		if expr.Len != nil {
+			break
+		}
+		elem, err := g.resolve(expr.Elt)
+		if err != nil {
+			return nil, err
+		}
+		t.kind, t.elem = kindSlice, elem
+		return t, nil
+	case *ast.MapType:
+		key, err := g.resolve(expr.Key)
+		if err != nil {
+			return nil, err
+		}
+		if underlying(key).class != "string" {
+			break
+		}
+		elem, err := g.resolve(expr.Value)
+		if err != nil {
+			return nil, err
+		}
+		t.kind, t.key, t.elem = kindMap, key, elem
+		return t, nil
+	case *ast.InterfaceType:
+		if len(expr.Methods.List) == 0 {
+			t.kind = kindAny
+			return t, nil
+		}
+	case *ast.StructType:
+		fields, err := g.structFields(expr)
+		if err != nil {
+			return nil, err
+		}
+		t.kind, t.fields = kindStruct, fields
+		return t, nil
+	}
+	return nil, fmt.Errorf("Unsupported type '%s'.", t.expr)
+}
+
+// underlying returns the type that a kindConv type is converted to.
+func underlying(t *gotype) *gotype {
+	for t.kind == kindConv {
+		t = t.elem
+	}
+	return t
+}
+
+func (g *generator) source(expr ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, g.fset, expr)
+	return buf.String()
+}
+
+// field is a struct field as the decoder sees it.
+type field struct {
+	name      string
+	key       string
+	omitempty bool
+	typ       *gotype
+}
+
+// structFields returns the fields of `st` that the decoder would set,
+// resolving their keys just like it does.
+func (g *generator) structFields(st *ast.StructType) ([]field, error) {
+	var fields []field
+	for _, f := range st.Fields.List {
+		names := make([]string, 0, len(f.Names))
+		for _, ident := range f.Names {
+			names = append(names, ident.Name)
+		}
+		if len(names) == 0 {
+			names = append(names, embeddedName(f.Type))
+		}
+
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			s, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(s)
+		}
+		tagValue, isTOML := tag.Lookup("toml")
+		if !isTOML && g.useJSON {
+			tagValue = tag.Get("json")
+		}
+		if tagValue == "-" {
+			continue
+		}
+		parts := strings.Split(tagValue, ",")
+
+		for _, name := range names {
+			fd := field{name: name, key: parts[0]}
+			for _, opt := range parts[1:] {
+				fd.omitempty = fd.omitempty || opt == "omitempty"
+				switch {
+				case strings.HasPrefix(opt, "alias="):
+					return nil, fmt.Errorf("Field '%s' has an alias, which "+
+						"generated code doesn't support.", name)
+				case opt == "secret" || opt == "deprecated":
+					return nil, fmt.Errorf("Field '%s' has the option '%s', "+
+						"which generated code doesn't support.", name, opt)
+				}
+			}
+			if !ast.IsExported(name) {
+				if isTOML && len(parts[0]) > 0 {
+					return nil, fmt.Errorf("Field '%s' is unexported, and "+
+						"therefore cannot be decoded.", name)
+				}
+				continue
+			}
+			switch {
+			case len(fd.key) > 0:
+			case g.mapper != nil:
+				fd.key = g.mapper(name)
+			default:
+				fd.key = name
+			}
+
+			typ, err := g.resolve(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("Field '%s': %s", name, err)
+			}
+			fd.typ = typ
+			fields = append(fields, fd)
+		}
+	}
+	return fields, nil
+}
+
+// embeddedName returns the field name of an embedded type.
+func embeddedName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// structMethods writes the UnmarshalTOML and MarshalTOML methods of the
+// named struct type `name`.
+func (g *generator) structMethods(name string) error {
+	st := g.named[name].Type.(*ast.StructType)
+	fields, err := g.structFields(st)
+	if err != nil {
+		return fmt.Errorf("Type '%s': %s", name, err)
+	}
+
+	w := &g.methodsOut
+	fmt.Fprintf(w, "\n// UnmarshalTOML implements toml.Unmarshaler.\n")
+	fmt.Fprintf(w, "func (v *%s) UnmarshalTOML(data interface{}) error {\n",
+		name)
+	fmt.Fprintf(w, "return v.DecodeTOML(nil, data)\n")
+	fmt.Fprintf(w, "}\n")
+
+	fmt.Fprintf(w, "\n// TOMLNaming implements toml.GeneratedUnmarshaler.\n")
+	fmt.Fprintf(w, "func (v *%s) TOMLNaming() (string, bool) {\n", name)
+	fmt.Fprintf(w, "return %q, %v\n", g.keys, g.useJSON)
+	fmt.Fprintf(w, "}\n")
+
+	fmt.Fprintf(w, "\n// DecodeTOML implements toml.GeneratedUnmarshaler.\n")
+	fmt.Fprintf(w, "func (v *%s) DecodeTOML(r *toml.Report, "+
+		"data interface{}) error {\n", name)
+	g.decodeStruct(w, fields)
+	fmt.Fprintf(w, "}\n")
+
+	fmt.Fprintf(w, "\n// MarshalTOML returns v as TOML data, in the form "+
+		"that UnmarshalTOML takes.\n")
+	fmt.Fprintf(w, "func (v *%s) MarshalTOML() (interface{}, error) {\n",
+		name)
+	g.encodeStruct(w, fields)
+	fmt.Fprintf(w, "}\n")
+	return nil
+}
+
+// decodeStruct writes the body of a function that decodes `data` into the
+// struct `*v`, with the fields `fields`, reporting to `r`.
+func (g *generator) decodeStruct(w *bytes.Buffer, fields []field) {
+	fmt.Fprintf(w, "s, tmap, err := r.Struct(data, v)\n")
+	fmt.Fprintf(w, "if err != nil {\nreturn err\n}\n")
+	for _, f := range fields {
+		fmt.Fprintf(w, "if k, ok, err := s.Field(%q, %q); err != nil {\n",
+			f.name, f.key)
+		fmt.Fprintf(w, "return err\n")
+		fmt.Fprintf(w, "} else if ok {\n")
+		fmt.Fprintf(w, "if err := %s; err != nil {\n",
+			g.decodeCall(f.typ, "s.At(k)", "&v."+f.name, "tmap[k]"))
+		fmt.Fprintf(w, "return s.FieldError(k, %q, err)\n", f.name)
+		fmt.Fprintf(w, "}\n}\n")
+	}
+	fmt.Fprintf(w, "s.Done()\n")
+	fmt.Fprintf(w, "return nil\n")
+}
+
+// encodeStruct writes the body of a function that encodes the struct `*v`,
+// with the fields `fields`.
+func (g *generator) encodeStruct(w *bytes.Buffer, fields []field) {
+	fmt.Fprintf(w, "tmap := make(map[string]interface{})\n")
+	for _, f := range fields {
+		cond := ""
+		if f.omitempty {
+			cond = nonEmpty(f.typ, "v."+f.name)
+		}
+		if len(cond) > 0 {
+			fmt.Fprintf(w, "if %s {\n", cond)
+		}
+		fmt.Fprintf(w, "if d, err := %s; err != nil {\n",
+			g.encodeCall(f.typ, "&v."+f.name))
+		fmt.Fprintf(w, "return nil, tomlgenAt(%q, err)\n", f.key)
+		fmt.Fprintf(w, "} else if d != nil {\n")
+		fmt.Fprintf(w, "tmap[%q] = d\n", f.key)
+		fmt.Fprintf(w, "}\n")
+		if len(cond) > 0 {
+			fmt.Fprintf(w, "}\n")
+		}
+	}
+	fmt.Fprintf(w, "return tmap, nil\n")
+}
+
+// nonEmpty returns an expression that is true unless `val`, of type `t`, is
+// empty in the sense of the `omitempty` option. It returns the empty string
+// if values of the type are never empty.
+func nonEmpty(t *gotype, val string) string {
+	switch u := underlying(t); u.kind {
+	case kindBasic:
+		switch u.class {
+		case "string":
+			return "len(" + val + ") != 0"
+		case "bool":
+			return val
+		}
+		return val + " != 0"
+	case kindSlice, kindMap:
+		return "len(" + val + ") != 0"
+	case kindPtr, kindAny:
+		return val + " != nil"
+	}
+	return ""
+}
+
+// decodeCall returns an expression that decodes `data` into the value of
+// type `t` that `ptr` points to, reporting to `r`, and evaluates to an error.
+func (g *generator) decodeCall(t *gotype, r, ptr, data string) string {
+	switch t.kind {
+	case kindGen:
+		return fmt.Sprintf("(%s).DecodeTOML(%s, %s)", ptr, r, data)
+	case kindMethod:
+		return fmt.Sprintf("%s.Unmarshal(%s, %s)", r, ptr, data)
+	case kindConv:
+		return g.decodeCall(t.elem, r,
+			fmt.Sprintf("(*%s)(%s)", t.elem.expr, ptr), data)
+	}
+	return fmt.Sprintf("tomlgenDecode%s(%s, %s, %s)", g.helper(t), r, ptr,
+		data)
+}
+
+// encodeCall returns an expression that encodes the value of type `t` that
+// `ptr` points to, and evaluates to its TOML data and an error. The data is
+// nil if there is no value, such as for a nil pointer.
+func (g *generator) encodeCall(t *gotype, ptr string) string {
+	switch t.kind {
+	case kindMethod, kindGen:
+		return fmt.Sprintf("(%s).MarshalTOML()", ptr)
+	case kindConv:
+		return g.encodeCall(t.elem,
+			fmt.Sprintf("(*%s)(%s)", t.elem.expr, ptr))
+	}
+	return fmt.Sprintf("tomlgenEncode%s(%s)", g.helper(t), ptr)
+}
+
+// helper returns the name of the pair of helper functions that decode and
+// encode values of type `t`, writing them first if needed.
+func (g *generator) helper(t *gotype) string {
+	if name, ok := g.helpers[t.expr]; ok {
+		return name
+	}
+	base := mangle(t)
+	name := base
+	for i := 2; len(g.taken[name]) > 0; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.helpers[t.expr] = name
+	g.taken[name] = t.expr
+
+	// Helpers of element types are written before this one.
+	var dec, enc bytes.Buffer
+	g.writeHelper(&dec, &enc, t)
+	fmt.Fprintf(&g.helpersOut, "\nfunc tomlgenDecode%s(r *toml.Report, "+
+		"v *%s, data interface{}) error {\n%s}\n", name, t.expr,
+		dec.String())
+	fmt.Fprintf(&g.helpersOut, "\nfunc tomlgenEncode%s(v *%s) "+
+		"(interface{}, error) {\n%s}\n", name, t.expr, enc.String())
+	return name
+}
+
+// mangle returns a name for the helper functions of type `t`.
+func mangle(t *gotype) string {
+	switch t.kind {
+	case kindTime:
+		return "Time"
+	case kindAny:
+		return "Any"
+	case kindStruct:
+		return "Struct"
+	case kindPtr:
+		return "Ptr" + mangle(t.elem)
+	case kindSlice:
+		return "Slice" + mangle(t.elem)
+	case kindMap:
+		if t.key.expr == "string" {
+			return "Map" + mangle(t.elem)
+		}
+		return "Map" + mangle(t.key) + mangle(t.elem)
+	}
+	return strings.ToUpper(t.expr[:1]) + t.expr[1:]
+}
+
+// writeHelper writes the bodies of the helper functions of type `t`.
+func (g *generator) writeHelper(dec, enc *bytes.Buffer, t *gotype) {
+	switch t.kind {
+	case kindBasic, kindTime:
+		assert, expected, conv := "string", "string", "*v"
+		switch {
+		case t.kind == kindTime:
+			assert, expected = "time.Time", "time.Time"
+		case t.class == "bool":
+			assert, expected = "bool", "bool"
+		case t.class == "int" || t.class == "uint":
+			assert, expected, conv = "int64", "integer", "int64(*v)"
+		case t.class == "float":
+			assert, expected, conv = "float64", "float", "float64(*v)"
+		}
+		fmt.Fprintf(dec, "d, ok := data.(%s)\n", assert)
+		fmt.Fprintf(dec, "if !ok {\n")
+		fmt.Fprintf(dec, "return fmt.Errorf(\"Expected %s but found "+
+			"'%%T'.\", data)\n", expected)
+		fmt.Fprintf(dec, "}\n")
+		switch {
+		case t.expr == "float32":
+			fmt.Fprintf(dec, "*v = r.Float32(d)\n")
+		case assert == t.expr:
+			fmt.Fprintf(dec, "*v = d\n")
+		default:
+			fmt.Fprintf(dec, "*v = %s(d)\n", t.expr)
+		}
+		fmt.Fprintf(dec, "return nil\n")
+		fmt.Fprintf(enc, "return %s, nil\n", conv)
+	case kindAny:
+		fmt.Fprintf(dec, "*v = data\nr.Decoded(data)\nreturn nil\n")
+		fmt.Fprintf(enc, "return *v, nil\n")
+	case kindStruct:
+		g.decodeStruct(dec, t.fields)
+		g.encodeStruct(enc, t.fields)
+	case kindPtr:
+		fmt.Fprintf(dec, "if *v == nil {\n*v = new(%s)\n}\n", t.elem.expr)
+		fmt.Fprintf(dec, "return %s\n",
+			g.decodeCall(t.elem, "r", "*v", "data"))
+		fmt.Fprintf(enc, "if *v == nil {\nreturn nil, nil\n}\n")
+		fmt.Fprintf(enc, "return %s\n", g.encodeCall(t.elem, "*v"))
+	case kindSlice:
+		fmt.Fprintf(dec, "slice, ok := data.([]interface{})\n")
+		fmt.Fprintf(dec, "if !ok {\n")
+		fmt.Fprintf(dec, "return fmt.Errorf(\"Expected slice but found "+
+			"'%%T'.\", data)\n")
+		fmt.Fprintf(dec, "}\n")
+		fmt.Fprintf(dec, "*v = make(%s, len(slice))\n", t.expr)
+		fmt.Fprintf(dec, "for i, d := range slice {\n")
+		fmt.Fprintf(dec, "if err := %s; err != nil {\nreturn err\n}\n",
+			g.decodeCall(t.elem, "r", "&(*v)[i]", "d"))
+		fmt.Fprintf(dec, "}\nreturn nil\n")
+
+		fmt.Fprintf(enc, "arr := make([]interface{}, 0, len(*v))\n")
+		fmt.Fprintf(enc, "for i := range *v {\n")
+		fmt.Fprintf(enc, "d, err := %s\n", g.encodeCall(t.elem, "&(*v)[i]"))
+		fmt.Fprintf(enc, "if err != nil {\nreturn nil, err\n}\n")
+		fmt.Fprintf(enc, "if d != nil {\narr = append(arr, d)\n}\n")
+		fmt.Fprintf(enc, "}\nreturn arr, nil\n")
+	case kindMap:
+		fmt.Fprintf(dec, "tmap, ok := data.(map[string]interface{})\n")
+		fmt.Fprintf(dec, "if !ok {\n")
+		fmt.Fprintf(dec, "return fmt.Errorf(\"Expected map but found "+
+			"'%%T'.\", data)\n")
+		fmt.Fprintf(dec, "}\n")
+		fmt.Fprintf(dec, "if *v == nil {\n*v = make(%s, len(tmap))\n}\n",
+			t.expr)
+		fmt.Fprintf(dec, "for k, d := range tmap {\n")
+		fmt.Fprintf(dec, "var e %s\n", t.elem.expr)
+		fmt.Fprintf(dec, "if err := %s; err != nil {\n",
+			g.decodeCall(t.elem, "r.Entry(k)", "&e", "d"))
+		fmt.Fprintf(dec, "return tomlgenAt(k, err)\n}\n")
+		fmt.Fprintf(dec, "(*v)[%s(k)] = e\n", t.key.expr)
+		fmt.Fprintf(dec, "}\nreturn nil\n")
+
+		fmt.Fprintf(enc, "tmap := make(map[string]interface{}, len(*v))\n")
+		fmt.Fprintf(enc, "for k := range *v {\n")
+		fmt.Fprintf(enc, "e := (*v)[k]\n")
+		fmt.Fprintf(enc, "d, err := %s\n", g.encodeCall(t.elem, "&e"))
+		fmt.Fprintf(enc, "if err != nil {\n")
+		fmt.Fprintf(enc, "return nil, tomlgenAt(string(k), err)\n}\n")
+		fmt.Fprintf(enc, "if d != nil {\ntmap[string(k)] = d\n}\n")
+		fmt.Fprintf(enc, "}\nreturn tmap, nil\n")
+	}
+}
+
+// runtime is written at the end of every generated file.
+const runtime = `
+// tomlgenAt returns err, which occurred at the key k, as a
+// *toml.DecodeError. Errors that already are one occurred deeper, so k is
+// prepended to their keys.
+func tomlgenAt(k string, err error) error {
+	if de, ok := err.(*toml.DecodeError); ok {
+		return &toml.DecodeError{Key: append(toml.Key{k}, de.Key...),
+			Err: de.Err}
+	}
+	return &toml.DecodeError{Key: toml.Key{k}, Err: err}
+}
+`
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExamplesAreGenerated(t *testing.T) {
+	output := filepath.Join("examples", "toml_gen.go")
+	g, err := newGenerator("examples", output, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := g.generate(strings.Split(
+		"tomlConfig,hard,implicit,readme1,readme2,multiDecoder", ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Fatalf("%s is out of date. Run 'go generate' in %s.",
+			output, filepath.Dir(output))
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := map[string]string{
+		"type a struct { X [2]int }": "Type 'a': Field 'X': " +
+			"Unsupported type '[2]int'.",
+		"type a struct { X chan int }": "Type 'a': Field 'X': " +
+			"Unsupported type 'chan int'.",
+		"type a struct { X map[int]string }": "Type 'a': Field 'X': " +
+			"Unsupported type 'map[int]string'.",
+		"type a struct { x int `toml:\"x\"` }": "Type 'a': Field 'x' is " +
+			"unexported, and therefore cannot be decoded.",
+		"type a struct { X int `toml:\"x,alias=y\"` }": "Type 'a': " +
+			"Field 'X' has an alias, which generated code doesn't support.",
+		"type a struct { X int `toml:\"x,secret\"` }": "Type 'a': " +
+			"Field 'X' has the option 'secret', which generated code " +
+			"doesn't support.",
+		"type a struct{}\nfunc (*a) UnmarshalTOML(interface{}) error " +
+			"{ return nil }": "Type 'a' already has an UnmarshalTOML or " +
+			"MarshalTOML method.",
+		"type a struct { B b }\ntype b string\n" +
+			"func (*b) UnmarshalTOML(interface{}) error { return nil }": "" +
+			"Type 'a': Field 'B': Type 'b' must have both an " +
+			"UnmarshalTOML and a MarshalTOML method, or neither.",
+	}
+	for src, want := range tests {
+		dir := t.TempDir()
+		err := ioutil.WriteFile(filepath.Join(dir, "a.go"),
+			[]byte("package a\n\n"+src+"\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := newGenerator(dir, filepath.Join(dir, "toml_gen.go"),
+			"", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = g.generate([]string{"a"})
+		if err == nil || err.Error() != want {
+			t.Errorf("Expected %q but got %v", want, err)
+		}
+	}
+}
+
+func TestGenerateKeys(t *testing.T) {
+	dir := t.TempDir()
+	src := "package a\n\ntype a struct {\n\tConnMax int\n" +
+		"\tName string `json:\"full_name\"`\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(src),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := newGenerator(dir, filepath.Join(dir, "toml_gen.go"),
+		"snake", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := g.generate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`s.Field("ConnMax", "conn_max")`,
+		`s.Field("Name", "full_name")`, `return "snake", true`} {
+
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("Expected the generated code to contain %s", want)
+		}
+	}
+}
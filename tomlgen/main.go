@@ -0,0 +1,80 @@
+// Command tomlgen generates code that decodes TOML data into Go structs, and
+// encodes them back, without reflection.
+//
+// Given the directory of a Go package and the names of struct types in it,
+// tomlgen writes a file with `UnmarshalTOML` and `MarshalTOML` methods for
+// each of them, and for the struct types they refer to. Since the types then
+// implement toml.Unmarshaler, the `Decode*` functions use the generated code
+// for them instead of reflection.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	flagTypes  = ""
+	flagOutput = ""
+	flagKeys   = ""
+	flagJSON   = false
+)
+
+func init() {
+	log.SetFlags(0)
+
+	flag.StringVar(&flagTypes, "type", flagTypes,
+		"A comma separated list of the struct types to generate code for.\n"+
+			"By default, code is generated for every struct type.")
+	flag.StringVar(&flagOutput, "o", flagOutput,
+		"The file to write to. By default, it is 'toml_gen.go' in the\n"+
+			"package directory.")
+	flag.StringVar(&flagKeys, "keys", flagKeys,
+		"The key mapper for fields without a tag: 'snake', 'kebab' or\n"+
+			"'lowercamel'. It should match the one used by the decoder.")
+	flag.BoolVar(&flagJSON, "json", flagJSON,
+		"When set, fields without a `toml` tag use their `json` tag.")
+
+	flag.Usage = usage
+}
+
+func usage() {
+	log.Printf("Usage: %s [flags] package-dir\n", path.Base(os.Args[0]))
+	flag.PrintDefaults()
+
+	os.Exit(1)
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+	}
+
+	dir := flag.Arg(0)
+	output := flagOutput
+	if len(output) == 0 {
+		output = filepath.Join(dir, "toml_gen.go")
+	}
+	g, err := newGenerator(dir, output, flagKeys, flagJSON)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var types []string
+	if len(flagTypes) > 0 {
+		types = strings.Split(flagTypes, ",")
+	}
+	src, err := g.generate(types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
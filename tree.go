@@ -0,0 +1,626 @@
+package toml
+
+import (
+	"io/ioutil"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Tree is a TOML document held as a tree of tables, for programs that would
+// rather look values up by key than decode them into Go values. Keys are
+// written as in TOML data, with dots between their parts, such as
+// "servers.alpha.ip". A part that holds dots or whitespace must be quoted,
+// as in `hosts."10.0.0.1".name`; see ParseKey.
+//
+// A table inside a Tree is itself a Tree, which shares its data with the
+// document it came from, so changes made through either are seen by both.
+// Keys given to the methods of such a Tree are relative to its table.
+type Tree struct {
+	p *parser
+
+	// the key of the tree's table in the document
+	context Key
+}
+
+// LoadTree parses TOML data into a Tree.
+func LoadTree(data string) (*Tree, error) {
+	return new(Decoder).loadTree(data, "")
+}
+
+// LoadTreeFile parses the TOML file at `fpath` into a Tree.
+func LoadTreeFile(fpath string) (*Tree, error) {
+	return new(Decoder).LoadTreeFile(fpath)
+}
+
+// LoadTree parses all bytes from the decoder's reader into a Tree, with the
+// options of the decoder.
+func (dec *Decoder) LoadTree() (*Tree, error) {
+	if dec.r == nil {
+		return nil, e("Decoder has no reader to decode from.")
+	}
+	bs, err := ioutil.ReadAll(dec.r)
+	if err != nil {
+		return nil, err
+	}
+	return dec.loadTree(string(bs), "")
+}
+
+// LoadTreeFile parses the TOML file at `fpath` into a Tree, with the options
+// of the decoder.
+func (dec *Decoder) LoadTreeFile(fpath string) (*Tree, error) {
+	bs, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return dec.loadTree(string(bs), fpath)
+}
+
+func (dec *Decoder) loadTree(data, fpath string) (*Tree, error) {
+	p, err := dec.parse(data, fpath)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{p: p}, nil
+}
+
+// ParseKey parses a key written as in TOML data, such as "a.b.c". Parts of
+// the key may be quoted like TOML strings, with the same escapes, so that
+// they can hold dots, whitespace and quotes. Whitespace around the parts is
+// ignored.
+func ParseKey(s string) (Key, error) {
+	var key Key
+	rest := s
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		var part string
+		if strings.HasPrefix(rest, `"`) {
+			end := quotedEnd(rest)
+			if end < 0 {
+				return nil, e("Key '%s' has an unterminated quoted part.", s)
+			}
+			p, err := parse("key = " + rest[:end+1])
+			if err != nil {
+				return nil, e("Key '%s' has an invalid quoted part %s.", s,
+					rest[:end+1])
+			}
+			part, rest = p.mapping["key"].(string), rest[end+1:]
+			rest = strings.TrimLeft(rest, " \t")
+		} else {
+			end := strings.IndexByte(rest, '.')
+			if end < 0 {
+				end = len(rest)
+			}
+			part, rest = strings.TrimRight(rest[:end], " \t"), rest[end:]
+			if len(part) == 0 {
+				return nil, e("Key '%s' has an empty part.", s)
+			}
+			if strings.ContainsAny(part, " \t\"") {
+				return nil, e("Key '%s' has a part with whitespace or "+
+					"quotes that isn't quoted.", s)
+			}
+		}
+		key = append(key, part)
+
+		if len(rest) == 0 {
+			return key, nil
+		}
+		if rest[0] != '.' {
+			return nil, e("Key '%s' has no dot after the part '%s'.", s, part)
+		}
+		rest = rest[1:]
+	}
+}
+
+// quotedEnd returns the index of the quote that ends the quoted string at
+// the start of `s`, or -1 if there is none.
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// table returns the table of the tree, or nil if it has been deleted from
+// the document.
+func (t *Tree) table() map[string]interface{} {
+	table := t.p.mapping
+	for _, k := range t.context {
+		var ok bool
+		if table, ok = table[k].(map[string]interface{}); !ok {
+			return nil
+		}
+	}
+	return table
+}
+
+// full returns the key `key`, relative to the tree, as a key of the
+// document.
+func (t *Tree) full(key Key) Key {
+	return append(t.context[:len(t.context):len(t.context)], key...)
+}
+
+// lookup returns the value of the key `s`, and the key in the document.
+func (t *Tree) lookup(s string) (interface{}, Key, bool) {
+	key, err := ParseKey(s)
+	if err != nil {
+		return nil, nil, false
+	}
+	var val interface{} = t.table()
+	for _, k := range key {
+		table, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		if val, ok = table[k]; !ok {
+			return nil, nil, false
+		}
+	}
+	return val, t.full(key), true
+}
+
+// Has returns true if the key `key` is defined.
+func (t *Tree) Has(key string) bool {
+	_, _, ok := t.lookup(key)
+	return ok
+}
+
+// Get returns the value of the key `key`, or nil if it isn't defined. Tables
+// are returned as a *Tree, arrays as a []interface{}, and other values as an
+// int64, float64, string, bool or time.Time.
+func (t *Tree) Get(key string) interface{} {
+	val, full, ok := t.lookup(key)
+	if !ok {
+		return nil
+	}
+	if _, ok := val.(map[string]interface{}); ok {
+		return &Tree{p: t.p, context: full}
+	}
+	return val
+}
+
+// get returns the value of the key `key`, or an error if it isn't defined.
+func (t *Tree) get(key string) (interface{}, Key, error) {
+	val, full, ok := t.lookup(key)
+	if !ok {
+		return nil, nil, e("Key '%s' is not defined.", key)
+	}
+	return val, full, nil
+}
+
+// mismatch returns the error for a value at the key `full` that isn't of
+// the type `expected`.
+func (t *Tree) mismatch(full Key, expected string, val interface{}) error {
+	return t.errorAt(full, badtype(expected, val))
+}
+
+// errorAt wraps the error `err` about the value at the key `full` in a
+// *DecodeError.
+func (t *Tree) errorAt(full Key, err error) error {
+	return &DecodeError{
+		Key:      full,
+		Position: t.p.positions[full.String()],
+		Err:      err,
+	}
+}
+
+// GetInt returns the integer value of the key `key`. It returns an error if
+// the key isn't defined, its value isn't an integer, or it is a *big.Int out
+// of the range of int64.
+func (t *Tree) GetInt(key string) (int64, error) {
+	val, full, err := t.get(key)
+	if err != nil {
+		return 0, err
+	}
+	switch num := val.(type) {
+	case int64:
+		return num, nil
+	case *big.Int:
+		if num.IsInt64() {
+			return num.Int64(), nil
+		}
+		return 0, t.errorAt(full, bigAsInt(num, reflect.TypeOf(int64(0))))
+	}
+	return 0, t.mismatch(full, "integer", val)
+}
+
+// GetFloat returns the float value of the key `key`. It returns an error if
+// the key isn't defined, its value isn't a float, or it is a *big.Float out
+// of the range of float64. A *big.Float is rounded to the nearest float64.
+func (t *Tree) GetFloat(key string) (float64, error) {
+	val, full, err := t.get(key)
+	if err != nil {
+		return 0, err
+	}
+	switch num := val.(type) {
+	case float64:
+		return num, nil
+	case *big.Float:
+		f, err := bigAsFloat(num, reflect.TypeOf(float64(0)))
+		if err != nil {
+			return 0, t.errorAt(full, err)
+		}
+		return f, nil
+	}
+	return 0, t.mismatch(full, "float", val)
+}
+
+// GetString returns the string value of the key `key`. It returns an error
+// if the key isn't defined or its value isn't a string.
+func (t *Tree) GetString(key string) (string, error) {
+	val, full, err := t.get(key)
+	if err != nil {
+		return "", err
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", t.mismatch(full, "string", val)
+	}
+	return s, nil
+}
+
+// GetBool returns the boolean value of the key `key`. It returns an error if
+// the key isn't defined or its value isn't a boolean.
+func (t *Tree) GetBool(key string) (bool, error) {
+	val, full, err := t.get(key)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return false, t.mismatch(full, "bool", val)
+	}
+	return b, nil
+}
+
+// GetDatetime returns the datetime value of the key `key`. It returns an
+// error if the key isn't defined or its value isn't a datetime.
+func (t *Tree) GetDatetime(key string) (time.Time, error) {
+	val, full, err := t.get(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	dt, ok := val.(time.Time)
+	if !ok {
+		return time.Time{}, t.mismatch(full, "datetime", val)
+	}
+	return dt, nil
+}
+
+// Type returns a string representation of the TOML type of the key `key`,
+// as (MetaData).Type does, or the empty string if the key isn't defined.
+func (t *Tree) Type(key string) string {
+	val, full, ok := t.lookup(key)
+	if !ok {
+		return ""
+	}
+	if typ, ok := t.p.types[full.String()]; ok {
+		return typ.typeString()
+	}
+	return typeOfValue(val).typeString()
+}
+
+// Position returns where the key `key` was defined in the TOML data. It is
+// the zero Position for keys that were set with Set.
+func (t *Tree) Position(key string) Position {
+	_, full, ok := t.lookup(key)
+	if !ok {
+		return Position{}
+	}
+	return t.p.positions[full.String()]
+}
+
+// Literal returns how the value of the key `key` was written in the TOML
+// data, as (MetaData).Literal does. Values set with Set get the literal they
+// would be written as by default.
+func (t *Tree) Literal(key string) Literal {
+	val, full, ok := t.lookup(key)
+	if !ok {
+		return Literal{}
+	}
+	return literal(t.p.literals, full, val)
+}
+
+// Keys returns every key in the tree, including tables, relative to the
+// tree. Keys from the TOML data come first, in the order in which they
+// appeared, followed by keys added with Set. Tables that were created
+// implicitly are left out, as with (MetaData).Keys.
+func (t *Tree) Keys() []Key {
+	keys := make([]Key, 0)
+	for _, key := range t.p.ordered {
+		if len(key) > len(t.context) && keyHasPrefix(key, t.context) {
+			keys = append(keys, key[len(t.context):])
+		}
+	}
+	return keys
+}
+
+// Redact marks the value of the key `key` as secret, so that neither Dump
+// nor the errors of Decode show it. Keys decoded into a Secret or a field
+// with the `secret` option by Decode are marked as well.
+func (t *Tree) Redact(key string) error {
+	parsed, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+	t.p.secrets[t.full(parsed).String()] = true
+	return nil
+}
+
+// Dump returns a description of every key in the tree, relative to the
+// tree, in the order of Keys, as (MetaData).Dump does. Secret values are
+// redacted.
+func (t *Tree) Dump() string {
+	keys := t.Keys()
+	for i, key := range keys {
+		keys[i] = t.full(key)
+	}
+	return dump(keys, len(t.context), func(key Key) (interface{}, tomlType) {
+		val, _ := valueAt(t.p.mapping, key)
+		return val, t.p.types[key.String()]
+	}, t.p.literals, t.p.secrets)
+}
+
+// keyHasPrefix returns true if `key` starts with all parts of `prefix`.
+func keyHasPrefix(key, prefix Key) bool {
+	if len(key) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if key[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Set sets the key `key` to `value`, replacing any value it had. Tables are
+// created as needed. The value may be a string, a boolean, a number, a
+// *big.Int, a *big.Float, a time.Time, a *Tree, or a slice or map[string] of
+// those. Numbers become TOML integers or floats, and maps and trees become
+// tables, which are copied.
+func (t *Tree) Set(key string, value interface{}) error {
+	k, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+	full := t.full(k)
+	val, err := treeValue(reflect.ValueOf(value))
+	if err != nil {
+		return e("%s", keyMessage(full, Position{}, err.Error()))
+	}
+
+	table := t.p.mapping
+	for i, part := range full[:len(full)-1] {
+		switch sub := table[part].(type) {
+		case map[string]interface{}:
+			table = sub
+		case nil:
+			table[part] = make(map[string]interface{})
+			table = table[part].(map[string]interface{})
+			t.p.types[full[:i+1].String()] = tomlHash
+			t.p.ordered = append(t.p.ordered, full[:i+1])
+		default:
+			return e("%s", keyMessage(full[:i+1],
+				t.p.positions[full[:i+1].String()],
+				"Cannot set a key in a value that isn't a table."))
+		}
+	}
+
+	// Replaced keys keep their place in the order of keys.
+	last, at := full[len(full)-1], len(t.p.ordered)
+	if _, ok := table[last]; ok {
+		at = t.remove(table, full)
+	}
+	table[last] = val
+	added := t.addMeta(nil, full, val)
+	t.p.ordered = append(t.p.ordered[:at],
+		append(added, t.p.ordered[at:]...)...)
+	return nil
+}
+
+// Delete deletes the key `key`, along with all keys in it if it is a table.
+// It returns false if the key isn't defined.
+func (t *Tree) Delete(key string) bool {
+	_, full, ok := t.lookup(key)
+	if !ok {
+		return false
+	}
+	table := t.p.mapping
+	for _, k := range full[:len(full)-1] {
+		table = table[k].(map[string]interface{})
+	}
+	t.remove(table, full)
+	return true
+}
+
+// remove deletes the key `full`, found in `table`, and its meta data. It
+// returns where the key, or the first key in it, was in the order of keys.
+func (t *Tree) remove(table map[string]interface{}, full Key) int {
+	last := full[len(full)-1]
+	if sub, ok := table[last].(map[string]interface{}); ok {
+		dropMeta(t.p, sub, full)
+	}
+	delete(table, last)
+	delete(t.p.types, full.String())
+	delete(t.p.positions, full.String())
+	delete(t.p.literals, full.String())
+	prefix := full.String() + "."
+	for s := range t.p.secrets {
+		if s == full.String() || strings.HasPrefix(s, prefix) {
+			delete(t.p.secrets, s)
+		}
+	}
+
+	at := -1
+	ordered := t.p.ordered[:0]
+	for _, k := range t.p.ordered {
+		if !keyHasPrefix(k, full) {
+			ordered = append(ordered, k)
+		} else if at < 0 {
+			at = len(ordered)
+		}
+	}
+	t.p.ordered = ordered
+	if at < 0 {
+		return len(ordered)
+	}
+	return at
+}
+
+// addMeta records the type of the value `val` set at the key `full`, and of
+// every key in it. It returns the keys appended to `keys`.
+func (t *Tree) addMeta(keys []Key, full Key, val interface{}) []Key {
+	t.p.types[full.String()] = typeOfValue(val)
+	keys = append(keys, full)
+	if table, ok := val.(map[string]interface{}); ok {
+		for _, k := range sortedKeys(table) {
+			keys = t.addMeta(keys, full.add(k), table[k])
+		}
+	}
+	return keys
+}
+
+// treeValue converts the Go value `rv` into a TOML value.
+func treeValue(rv reflect.Value) (interface{}, error) {
+	if !rv.IsValid() {
+		return nil, e("TOML has no null value.")
+	}
+	switch v := rv.Interface().(type) {
+	case *big.Int, *big.Float:
+		return v, nil
+	case *Tree:
+		table := v.table()
+		if table == nil {
+			return nil, e("Tree has been deleted from its document.")
+		}
+		return treeValue(reflect.ValueOf(table))
+	case time.Time:
+		return v, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if rv.IsNil() {
+			return nil, e("TOML has no null value.")
+		}
+		return treeValue(rv.Elem())
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+
+		if rv.Uint() > math.MaxInt64 {
+			return nil, e("Integer '%d' is out of the range of 64-bit "+
+				"signed integers.", rv.Uint())
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Slice, reflect.Array:
+		array := make([]interface{}, rv.Len())
+		for i := range array {
+			val, err := treeValue(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 && !typeEqual(typeOfValue(array[0]),
+				typeOfValue(val)) {
+
+				return nil, e("Array contains values of type '%s' and "+
+					"'%s', but arrays must be homogeneous.",
+					typeOfValue(array[0]), typeOfValue(val))
+			}
+			array[i] = val
+		}
+		return array, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		table := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			val, err := treeValue(rv.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+			table[k.String()] = val
+		}
+		return table, nil
+	}
+	return nil, e("Unsupported type '%s'.", rv.Type())
+}
+
+// typeOfValue returns the TOML type of a value produced by the parser.
+func typeOfValue(val interface{}) tomlType {
+	switch val.(type) {
+	case int64, *big.Int:
+		return tomlInteger
+	case float64, *big.Float:
+		return tomlFloat
+	case string:
+		return tomlString
+	case bool:
+		return tomlBool
+	case time.Time:
+		return tomlDatetime
+	case []interface{}:
+		return tomlArray
+	}
+	return tomlHash
+}
+
+// Decode decodes the tree into the pointer `v`, just like Decode would, with
+// the options of the Decoder the tree was loaded with. Keys in the MetaData
+// returned and in errors are those of the whole document.
+func (t *Tree) Decode(v interface{}) (MetaData, error) {
+	table := t.table()
+	if table == nil {
+		return MetaData{}, e("Tree has been deleted from its document.")
+	}
+	keys := make([]Key, 0)
+	for _, key := range t.p.ordered {
+		if len(key) > len(t.context) && keyHasPrefix(key, t.context) {
+			keys = append(keys, key)
+		}
+	}
+	md := MetaData{
+		mapping:   t.p.mapping,
+		types:     t.p.types,
+		keys:      keys,
+		decoded:   make(map[string]bool),
+		positions: t.p.positions,
+		literals:  t.p.literals,
+		secrets:   t.p.secrets,
+		warnings:  new([]Warning),
+		context:   t.full(nil),
+		dec:       t.p.dec,
+	}
+	for _, w := range t.p.warnings {
+		if keyHasPrefix(w.Key, t.context) {
+			*md.warnings = append(*md.warnings, w)
+		}
+	}
+	err := md.unify(table, rvalue(v))
+	if err == nil {
+		err = md.validate(table, rvalue(v))
+	}
+	if err == nil {
+		err = md.promoted(0)
+	}
+	md.context = nil
+	return md, err
+}
@@ -0,0 +1,293 @@
+package toml
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var treeData = `
+title = "example"
+
+[database]
+server = "192.168.1.1"
+ports = [8001, 8002]
+enabled = true
+
+[servers.alpha]
+ip = "10.0.0.1"
+10.0.0.1 = "alpha"
+`
+
+func TestParseKey(t *testing.T) {
+	tests := map[string]Key{
+		"a":               {"a"},
+		"a.b.c":           {"a", "b", "c"},
+		" a . b ":         {"a", "b"},
+		`a."b.c".d`:       {"a", "b.c", "d"},
+		`"a b"`:           {"a b"},
+		`"say \"hi\"".x`:  {`say "hi"`, "x"},
+		`"tab\there"."é"`: {"tab\there", "é"},
+	}
+	for s, want := range tests {
+		got, err := ParseKey(s)
+		if err != nil {
+			t.Errorf("%q: %s", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: Expected %q but got %q", s, want, got)
+		}
+	}
+
+	bad := map[string]string{
+		"":     "Key '' has an empty part.",
+		"a..b": "Key 'a..b' has an empty part.",
+		"a.":   "Key 'a.' has an empty part.",
+		"a b": "Key 'a b' has a part with whitespace or quotes that " +
+			"isn't quoted.",
+		`"a`:   `Key '"a' has an unterminated quoted part.`,
+		`"a"b`: `Key '"a"b' has no dot after the part 'a'.`,
+		`"\q"`: `Key '"\q"' has an invalid quoted part "\q".`,
+	}
+	for s, want := range bad {
+		_, err := ParseKey(s)
+		if err == nil || err.Error() != want {
+			t.Errorf("%q: Expected %q but got %v", s, want, err)
+		}
+	}
+}
+
+func TestTreeGet(t *testing.T) {
+	tree, err := LoadTree(treeData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := tree.GetString("database.server"); err != nil ||
+		s != "192.168.1.1" {
+
+		t.Errorf("Expected '192.168.1.1' but got %q (%v)", s, err)
+	}
+	if b, err := tree.GetBool("database.enabled"); err != nil || !b {
+		t.Errorf("Expected true but got %v (%v)", b, err)
+	}
+	ports := tree.Get("database.ports")
+	if !reflect.DeepEqual(ports, []interface{}{int64(8001), int64(8002)}) {
+		t.Errorf("Expected the ports but got %#v", ports)
+	}
+	if s, _ := tree.GetString(`servers.alpha."10.0.0.1"`); s != "alpha" {
+		t.Errorf("Expected a quoted key to be found, but got %q", s)
+	}
+	if tree.Has("servers.beta") || tree.Has("title.x") || tree.Has("a..b") {
+		t.Errorf("Expected missing keys not to be found")
+	}
+	if tree.Get("nope") != nil {
+		t.Errorf("Expected nil for a missing key")
+	}
+
+	servers, ok := tree.Get("servers").(*Tree)
+	if !ok {
+		t.Fatalf("Expected a table to be a *Tree")
+	}
+	if s, _ := servers.GetString("alpha.ip"); s != "10.0.0.1" {
+		t.Errorf("Expected keys of a table to be relative, but got %q", s)
+	}
+
+	types := map[string]string{
+		"title":          "String",
+		"database":       "Hash",
+		"database.port":  "",
+		"database.ports": "Array",
+		"servers":        "Hash",
+	}
+	for key, want := range types {
+		if got := tree.Type(key); got != want {
+			t.Errorf("%s: Expected type %q but got %q", key, want, got)
+		}
+	}
+	if pos := tree.Position("database.ports"); pos.Line != 6 {
+		t.Errorf("Expected 'database.ports' on line 6, but got %v", pos)
+	}
+
+	_, err = tree.GetInt("database.server")
+	want := "Near line 5, key 'database.server': Expected integer but " +
+		"found 'string'."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+	_, err = tree.GetFloat("database.missing")
+	want = "Key 'database.missing' is not defined."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+}
+
+func TestTreeSetDelete(t *testing.T) {
+	tree, err := LoadTree(treeData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dob := time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)
+	sets := []struct {
+		key string
+		val interface{}
+	}{
+		{"title", "changed"},
+		{"owner.dob", dob},
+		{"database.ports", []int{1, 2}},
+		{`servers."b.c".ip`, "10.0.0.2"},
+		{"limits", map[string]interface{}{"max": uint8(5)}},
+		{"ratio", float32(0.5)},
+	}
+	for _, set := range sets {
+		if err := tree.Set(set.key, set.val); err != nil {
+			t.Fatalf("%s: %s", set.key, err)
+		}
+	}
+	if s, _ := tree.GetString("title"); s != "changed" {
+		t.Errorf("Expected the title to be replaced, but got %q", s)
+	}
+	if d, _ := tree.GetDatetime("owner.dob"); !d.Equal(dob) {
+		t.Errorf("Expected %v but got %v", dob, d)
+	}
+	if n, _ := tree.GetInt("limits.max"); n != 5 {
+		t.Errorf("Expected 5 but got %d", n)
+	}
+	if f, _ := tree.GetFloat("ratio"); f != 0.5 {
+		t.Errorf("Expected 0.5 but got %v", f)
+	}
+	if typ := tree.Type("limits.max"); typ != "Integer" {
+		t.Errorf("Expected the type of a set table's key, but got %q", typ)
+	}
+	if pos := tree.Position("title"); pos.Line != 0 {
+		t.Errorf("Expected a set key to have no position, but got %v", pos)
+	}
+
+	// Tables set from a tree are copies.
+	if err := tree.Set("copy", tree.Get("servers")); err != nil {
+		t.Fatal(err)
+	}
+	tree.Get("servers").(*Tree).Set("alpha.ip", "10.0.0.9")
+	if s, _ := tree.GetString("copy.alpha.ip"); s != "10.0.0.1" {
+		t.Errorf("Expected the copied table to be unchanged, but got %q", s)
+	}
+
+	if !tree.Delete("servers") || tree.Delete("servers") {
+		t.Errorf("Expected Delete to report whether the key existed")
+	}
+	if tree.Has("servers.alpha.ip") || tree.Type("servers.alpha") != "" {
+		t.Errorf("Expected the keys in a deleted table to be gone")
+	}
+
+	var keys []string
+	for _, k := range tree.Keys() {
+		keys = append(keys, k.String())
+	}
+	want := "title, database, database.server, database.ports, " +
+		"database.enabled, owner, owner.dob, limits, limits.max, ratio, " +
+		"copy, copy.alpha, copy.alpha.10.0.0.1, copy.alpha.ip, copy.b.c, " +
+		"copy.b.c.ip"
+	if got := strings.Join(keys, ", "); got != want {
+		t.Errorf("Expected keys\n%s\nbut got\n%s", want, got)
+	}
+
+	errs := map[string]interface{}{
+		"title.x": "Key 'title': Cannot set a key in a value that isn't " +
+			"a table.",
+		"nil": "Key 'nil': TOML has no null value.",
+		"mixed": "Key 'mixed': Array contains values of type 'Integer' " +
+			"and 'String', but arrays must be homogeneous.",
+		"chan": "Key 'chan': Unsupported type 'chan int'.",
+		"huge": "Key 'huge': Integer '18446744073709551615' is out of " +
+			"the range of 64-bit signed integers.",
+	}
+	vals := map[string]interface{}{
+		"title.x": 1,
+		"nil":     nil,
+		"mixed":   []interface{}{1, "a"},
+		"chan":    make(chan int),
+		"huge":    uint64(1<<64 - 1),
+	}
+	for key, want := range errs {
+		err := tree.Set(key, vals[key])
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: Expected %q but got %v", key, want, err)
+		}
+	}
+}
+
+func TestTreeGetBig(t *testing.T) {
+	tree, err := LoadTree("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	values := map[string]interface{}{
+		"small":      big.NewInt(42),
+		"huge":       huge,
+		"pi":         big.NewFloat(3.5),
+		"overflowed": new(big.Float).SetMantExp(big.NewFloat(1), 2000),
+	}
+	for key, val := range values {
+		if err := tree.Set(key, val); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if num, err := tree.GetInt("small"); err != nil || num != 42 {
+		t.Errorf("Expected 42 but got %d (%v)", num, err)
+	}
+	_, err = tree.GetInt("huge")
+	want := "Key 'huge': Integer '123456789012345678901234567890' is out " +
+		"of the range of int64."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+	if num, err := tree.GetFloat("pi"); err != nil || num != 3.5 {
+		t.Errorf("Expected 3.5 but got %v (%v)", num, err)
+	}
+	_, err = tree.GetFloat("overflowed")
+	if err == nil || !strings.Contains(err.Error(), "out of the range") {
+		t.Errorf("Expected a range error but got %v", err)
+	}
+}
+
+func TestTreeDecode(t *testing.T) {
+	tree, err := LoadTree(treeData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Set("database.ports", []int{9001})
+
+	var db struct {
+		Server string
+		Ports  []int
+	}
+	md, err := tree.Get("database").(*Tree).Decode(&db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Server != "192.168.1.1" || !reflect.DeepEqual(db.Ports, []int{9001}) {
+		t.Errorf("Unexpected value %+v", db)
+	}
+	if !md.IsDefined("database", "server") {
+		t.Errorf("Expected the MetaData to have the keys of the document")
+	}
+	undecoded := md.Undecoded()
+	if len(undecoded) != 1 || undecoded[0].String() != "database.enabled" {
+		t.Errorf("Expected only 'database.enabled' to be undecoded, but "+
+			"got %v", undecoded)
+	}
+
+	var bad struct{ Enabled int }
+	_, err = tree.Get("database").(*Tree).Decode(&bad)
+	want := "Near line 7, key 'database.enabled': Type mismatch for " +
+		"'struct { Enabled int }.Enabled': Expected integer but found 'bool'."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+}
@@ -0,0 +1,322 @@
+package toml
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// rule is a constraint of a `validate` struct tag.
+type rule struct {
+	name string
+	arg  string
+
+	bound *big.Rat       // for min and max
+	set   []string       // for oneof
+	re    *regexp.Regexp // for regexp
+}
+
+// parseRules parses a `validate` struct tag, such as "min=1,max=65535".
+// Since regular expressions may have commas, a regexp rule runs to the end
+// of the tag.
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for len(tag) > 0 {
+		part := tag
+		tag = ""
+		if i := strings.IndexByte(part, ','); i >= 0 &&
+			!strings.HasPrefix(part, "regexp=") {
+
+			part, tag = part[:i], part[i+1:]
+		}
+
+		r := rule{name: part}
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			r.name, r.arg = part[:i], part[i+1:]
+		}
+		switch r.name {
+		case "nonempty":
+			if len(r.arg) > 0 {
+				return nil, e("Rule 'nonempty' takes no argument.")
+			}
+		case "min", "max":
+			var ok bool
+			if r.bound, ok = new(big.Rat).SetString(r.arg); !ok {
+				return nil, e("Rule '%s' needs a number, not '%s'.",
+					r.name, r.arg)
+			}
+		case "oneof":
+			if r.set = strings.Fields(r.arg); len(r.set) == 0 {
+				return nil, e("Rule 'oneof' needs at least one value.")
+			}
+		case "regexp":
+			var err error
+			if r.re, err = regexp.Compile(r.arg); err != nil {
+				return nil, e("Rule 'regexp' has an invalid pattern: %s", err)
+			}
+		default:
+			return nil, e("Unknown rule '%s'.", r.name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// check returns a message saying how `rv` breaks the rule, or the empty
+// string if it doesn't. The message doesn't show the value if `secret` is
+// true. Nil pointers and absent Optional values only break nonempty rules.
+// An error is returned if the rule doesn't apply to values of the type of
+// `rv`.
+func (r rule) check(rv reflect.Value, secret bool) (string, error) {
+	show := func(text string) string {
+		if secret {
+			return redacted
+		}
+		return text
+	}
+
+	rv, present := ruleValue(rv)
+	if r.name == "nonempty" {
+		if !present || isEmpty(rv) || rv.IsZero() {
+			return "Value is empty.", nil
+		}
+		return "", nil
+	}
+	if !present {
+		return "", nil
+	}
+
+	switch r.name {
+	case "min", "max":
+		n, what, text, ok := measure(rv)
+		if !ok {
+			break
+		}
+		if r.name == "min" && n.Cmp(r.bound) < 0 {
+			return fmt.Sprintf("%s %s is less than the minimum of %s.",
+				what, show(text), r.arg), nil
+		}
+		if r.name == "max" && n.Cmp(r.bound) > 0 {
+			return fmt.Sprintf("%s %s is greater than the maximum of %s.",
+				what, show(text), r.arg), nil
+		}
+		return "", nil
+	case "oneof":
+		s, ok := scalarText(rv)
+		if !ok {
+			break
+		}
+		for _, allowed := range r.set {
+			if s == allowed {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("Value '%s' is not one of '%s'.",
+			show(s), strings.Join(r.set, "', '")), nil
+	case "regexp":
+		if rv.Kind() != reflect.String {
+			break
+		}
+		if !r.re.MatchString(rv.String()) {
+			return fmt.Sprintf("Value '%s' doesn't match '%s'.",
+				show(rv.String()), r.arg), nil
+		}
+		return "", nil
+	}
+	return "", e("Rule '%s' doesn't apply to values of type %s.",
+		r.name, rv.Type())
+}
+
+// ruleValue returns the value that rules check in place of `rv`, following
+// pointers and Optional values. It returns false if there is no such value.
+func ruleValue(rv reflect.Value) (reflect.Value, bool) {
+	for {
+		switch {
+		case rv.Kind() == reflect.Ptr && !isBigType(rv.Type().Elem()):
+			if rv.IsNil() {
+				return rv, false
+			}
+			rv = rv.Elem()
+		case optionalElem(rv.Type()) != nil:
+			opt := reflect.New(rv.Type())
+			opt.Elem().Set(rv)
+			v, set := opt.Interface().(optional).getValue()
+			if !set {
+				return rv, false
+			}
+			rv = v
+		default:
+			return rv, true
+		}
+	}
+}
+
+// measure returns the number that min and max rules compare with their
+// bounds: the value of a number, or the length of a string, slice or map.
+// It also returns what the number is and how to write it in messages.
+func measure(rv reflect.Value) (*big.Rat, string, string, bool) {
+	n := new(big.Rat)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		n.SetInt64(rv.Int())
+		return n, "Value", strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		n.SetInt(new(big.Int).SetUint64(rv.Uint()))
+		return n, "Value", strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		if n.SetFloat64(rv.Float()) == nil {
+			// NaN is neither less nor greater than any bound.
+			return nil, "", "", false
+		}
+		return n, "Value", strconv.FormatFloat(rv.Float(), 'g', -1, 64), true
+	case reflect.String:
+		length := utf8.RuneCountInString(rv.String())
+		return n.SetInt64(int64(length)), "Length", strconv.Itoa(length), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return n.SetInt64(int64(rv.Len())), "Length", strconv.Itoa(rv.Len()),
+			true
+	}
+
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, "", "", false
+		}
+		rv = rv.Elem()
+	}
+	if !rv.CanAddr() {
+		v := reflect.New(rv.Type()).Elem()
+		v.Set(rv)
+		rv = v
+	}
+	switch num := rv.Addr().Interface().(type) {
+	case *big.Int:
+		return n.SetInt(num), "Value", num.String(), true
+	case *big.Float:
+		if num.IsInf() {
+			return nil, "", "", false
+		}
+		num.Rat(n)
+		return n, "Value", num.Text('g', -1), true
+	case *big.Rat:
+		return n.Set(num), "Value", num.RatString(), true
+	}
+	return nil, "", "", false
+}
+
+// scalarText returns the text that oneof rules compare with their values.
+func scalarText(rv reflect.Value) (string, bool) {
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), true
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), true
+	}
+	return "", false
+}
+
+// validate checks the value `rv`, decoded from `data` at the key of the
+// current context, against the `validate` tags of the struct fields in it.
+// Every value that breaks a rule is reported in a *StrictError.
+func (md *MetaData) validate(data interface{}, rv reflect.Value) error {
+	c := newChecker(md.dec, nil, md.positions)
+	c.secrets = md.secrets
+	if err := c.validate(data, rv, md.context); err != nil {
+		return err
+	}
+	return c.err()
+}
+
+// validate records a violation for every value in `rv` that breaks a rule.
+// An error is returned if a `validate` tag is invalid.
+func (c *checker) validate(data interface{}, rv reflect.Value, key Key) error {
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return c.validate(data, rv.Elem(), key)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		tmap, _ := data.(map[string]interface{})
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, k := range keys {
+			err := c.validate(tmap[k.String()], rv.MapIndex(k),
+				key.add(k.String()))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if optionalElem(rv.Type()) != nil {
+			if v, present := ruleValue(rv); present {
+				return c.validate(data, v, key)
+			}
+			return nil
+		}
+		return c.validateStruct(data, rv, key)
+	}
+	return nil
+}
+
+func (c *checker) validateStruct(
+	data interface{}, rv reflect.Value, key Key) error {
+
+	tmap, _ := data.(map[string]interface{})
+	rt := rv.Type()
+	ix := newKeyIndex(tmap, !c.dec.NoCaseFolding)
+	for i, f := range structFields(rt, c.dec.KeyMapper, c.dec.UseJSONTags) {
+		sft, opts := f.sft, f.opts
+		if len(sft.PkgPath) > 0 || opts.skip {
+			continue
+		}
+		name := opts.name
+		if k, ok, _, _ := matchField(ix, f); ok {
+			name = k
+		}
+		fkey := key.add(name)
+		fv := rv.Field(i)
+		if opts.secret {
+			c.secrets[fkey.String()] = true
+		}
+		secret := isSecret(c.secrets, fkey) || isSecretType(sft.Type)
+
+		if f.rulesErr != nil {
+			return e("Field '%s.%s' has an invalid validate tag: %s",
+				rt.String(), sft.Name, f.rulesErr)
+		}
+		for _, r := range f.rules {
+			msg, err := r.check(fv, secret)
+			if err != nil {
+				return e("Field '%s.%s' has an invalid validate tag: %s",
+					rt.String(), sft.Name, err)
+			}
+			if len(msg) > 0 {
+				c.violation(fkey, "%s", msg)
+			}
+		}
+		if err := c.validate(tmap[name], fv, fkey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package toml
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+var validateData = `
+level = "trace"
+name = "Web1"
+
+[server]
+port = 70000
+hosts = []
+`
+
+type validateConfig struct {
+	Level  string `validate:"oneof=debug info warn"`
+	Name   string `validate:"nonempty,regexp=^[a-z]+[0-9]{0,3}$"`
+	Server struct {
+		Port  int      `validate:"min=1,max=65535"`
+		Hosts []string `validate:"nonempty"`
+	}
+	Workers *int `validate:"min=1"`
+}
+
+func TestValidate(t *testing.T) {
+	var v validateConfig
+	_, err := Decode(validateData, &v)
+	se, ok := err.(*StrictError)
+	if !ok {
+		t.Fatalf("Expected a *StrictError but got %v", err)
+	}
+
+	want := []string{
+		"Near line 2, key 'level': " +
+			"Value 'trace' is not one of 'debug', 'info', 'warn'.",
+		"Near line 3, key 'name': " +
+			"Value 'Web1' doesn't match '^[a-z]+[0-9]{0,3}$'.",
+		"Near line 6, key 'server.port': " +
+			"Value 70000 is greater than the maximum of 65535.",
+		"Near line 7, key 'server.hosts': Value is empty.",
+	}
+	if len(se.Violations) != len(want) {
+		t.Fatalf("Expected %d violations but got:\n%s", len(want), err)
+	}
+	for i, v := range se.Violations {
+		if v.String() != want[i] {
+			t.Errorf("Expected %q but got %q", want[i], v.String())
+		}
+	}
+	if v.Server.Port != 70000 {
+		t.Errorf("Expected values to be decoded before validation")
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	var v validateConfig
+	_, err := Decode(`
+level = "info"
+name = "web12"
+workers = 4
+
+[server]
+port = 8080
+hosts = ["a"]
+`, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateAbsent(t *testing.T) {
+	var v struct {
+		Name    string        `validate:"nonempty"`
+		Timeout Optional[int] `validate:"min=1"`
+		Retries *int          `validate:"nonempty"`
+	}
+	_, err := Decode(``, &v)
+	se, ok := err.(*StrictError)
+	if !ok || len(se.Violations) != 2 {
+		t.Fatalf("Expected 2 violations but got %v", err)
+	}
+	if se.Violations[0].String() != "Key 'Name': Value is empty." {
+		t.Errorf("Expected absent keys to be named, but got %q",
+			se.Violations[0])
+	}
+}
+
+func TestValidateLengthsAndNumbers(t *testing.T) {
+	var v struct {
+		Code  string   `validate:"min=2,max=3"`
+		Tags  []string `validate:"max=1"`
+		Ratio float64  `validate:"min=0.5"`
+		Big   big.Int  `validate:"max=100"`
+		Debug bool     `validate:"oneof=false"`
+	}
+	_, err := Decode(`
+code = "ü"
+tags = ["a", "b"]
+ratio = 0.25
+big = 101
+debug = true
+`, &v)
+	want := strings.Join([]string{
+		"Near line 2, key 'code': Length 1 is less than the minimum of 2.",
+		"Near line 3, key 'tags': Length 2 is greater than the maximum of 1.",
+		"Near line 4, key 'ratio': Value 0.25 is less than the minimum of 0.5.",
+		"Near line 5, key 'big': Value 101 is greater than the maximum of 100.",
+		"Near line 6, key 'debug': Value 'true' is not one of 'false'.",
+	}, "\n")
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected:\n%s\nbut got:\n%v", want, err)
+	}
+}
+
+func TestValidateMaps(t *testing.T) {
+	var v struct {
+		Servers map[string]struct {
+			IP string `validate:"nonempty"`
+		}
+	}
+	_, err := Decode(`
+[servers.alpha]
+ip = "10.0.0.1"
+
+[servers.beta]
+dc = "eqdc10"
+`, &v)
+	want := "Key 'servers.beta.IP': Value is empty."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+}
+
+func TestValidatePrimitiveDecode(t *testing.T) {
+	var v struct{ Server Primitive }
+	md, err := Decode(validateData, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server struct {
+		Port int `validate:"max=65535"`
+	}
+	err = md.PrimitiveDecode(v.Server, &server)
+	want := "Near line 6, key 'server.port': " +
+		"Value 70000 is greater than the maximum of 65535."
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+}
+
+func TestValidateBadTags(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{&struct {
+			A int `validate:"between=1"`
+		}{}, "Unknown rule 'between'."},
+		{&struct {
+			A int `validate:"min=one"`
+		}{}, "Rule 'min' needs a number, not 'one'."},
+		{&struct {
+			A int `validate:"regexp=^a"`
+		}{}, "Rule 'regexp' doesn't apply to values of type int."},
+		{&struct {
+			A int `validate:"oneof="`
+		}{}, "Rule 'oneof' needs at least one value."},
+	}
+	for _, test := range tests {
+		_, err := Decode(`a = 1`, test.v)
+		if err == nil || !strings.HasSuffix(err.Error(), test.want) {
+			t.Errorf("Expected an error ending in %q but got %v",
+				test.want, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package toml
+
+import (
+	"fmt"
+)
+
+// Warning is a problem with TOML data that doesn't stop it from being
+// decoded, such as the use of a deprecated key.
+type Warning struct {
+	// What the problem is.
+	Kind WarningKind
+
+	// The key the warning is about.
+	Key Key
+
+	// Where the key was defined, if known.
+	Position Position
+
+	Message string
+}
+
+func (w Warning) String() string {
+	return keyMessage(w.Key, w.Position, w.Message)
+}
+
+// WarningKind is the kind of problem a Warning is about.
+type WarningKind int
+
+const (
+	// UnknownKey is a key in a table decoded into a struct that doesn't
+	// match any field of the struct. Such keys are also reported by
+	// (MetaData).Undecoded.
+	UnknownKey WarningKind = iota + 1
+
+	// DeprecatedKey is a key of a field with the `deprecated` option.
+	DeprecatedKey
+
+	// CaseFoldedKey is a key that matches a struct field only case
+	// insensitively.
+	CaseFoldedKey
+
+	// LossyConversion is a number that is rounded to fit a Go value, such
+	// as a float with more digits than a float64 holds, or a float decoded
+	// into a float32.
+	LossyConversion
+)
+
+func (k WarningKind) String() string {
+	switch k {
+	case UnknownKey:
+		return "unknown key"
+	case DeprecatedKey:
+		return "deprecated key"
+	case CaseFoldedKey:
+		return "case folded key"
+	case LossyConversion:
+		return "lossy conversion"
+	}
+	return "none"
+}
+
+// Warnings returns the warnings found while parsing and decoding, in the
+// order they were found. Warnings found by (MetaData).PrimitiveDecode are
+// included.
+func (md MetaData) Warnings() []Warning {
+	if md.warnings == nil {
+		return nil
+	}
+	return *md.warnings
+}
+
+func (md *MetaData) warn(kind WarningKind, key Key, format string,
+	v ...interface{}) {
+
+	fullKey := make(Key, len(key))
+	copy(fullKey, key)
+	*md.warnings = append(*md.warnings, Warning{
+		Kind:     kind,
+		Key:      fullKey,
+		Position: md.positions[fullKey.String()],
+		Message:  fmt.Sprintf(format, v...),
+	})
+}
+
+// deprecated warns that the deprecated key `key` was used. If it is an
+// alias, `name` is the key to use instead.
+func (md *MetaData) deprecated(key Key, name string, alias bool) {
+	if alias {
+		md.warn(DeprecatedKey, key, "Key '%s' is deprecated. Use '%s' "+
+			"instead.", key[len(key)-1], name)
+		return
+	}
+	md.warn(DeprecatedKey, key, "Key '%s' is deprecated.", key[len(key)-1])
+}
+
+// promoted returns the warnings found since the first `from` of them whose
+// kinds are among the decoder's PromoteWarnings as a *StrictError, or nil
+// if there are none.
+func (md *MetaData) promoted(from int) error {
+	if len(md.dec.PromoteWarnings) == 0 {
+		return nil
+	}
+	c := newChecker(md.dec, nil, md.positions)
+	for _, w := range (*md.warnings)[from:] {
+		for _, kind := range md.dec.PromoteWarnings {
+			if w.Kind == kind {
+				c.violations = append(c.violations, Violation{
+					Key:      w.Key,
+					Position: w.Position,
+					Message:  w.Message,
+				})
+				break
+			}
+		}
+	}
+	return c.err()
+}
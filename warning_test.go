@@ -0,0 +1,128 @@
+package toml
+
+import (
+	"strings"
+	"testing"
+)
+
+var warningData = `
+Name = "web"
+typo = 1
+pi = 3.14159265358979323846264
+ratio = 0.1
+
+[extra]
+x = 1
+`
+
+type warningConfig struct {
+	Name  string  `toml:"name"`
+	Pi    float64 `toml:"pi"`
+	Ratio float32 `toml:"ratio"`
+}
+
+func TestWarnings(t *testing.T) {
+	var v warningConfig
+	md, err := Decode(warningData, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind WarningKind
+		msg  string
+	}{
+		{LossyConversion, "Near line 4, key 'pi': Float " +
+			"3.14159265358979323846264 is rounded to 3.141592653589793, " +
+			"the nearest 64-bit float."},
+		{CaseFoldedKey, "Near line 2, key 'Name': Key 'Name' matches the " +
+			"field 'toml.warningConfig.Name' only case insensitively."},
+		{LossyConversion, "Near line 5, key 'ratio': Float 0.1 is rounded " +
+			"to 0.10000000149011612 to fit float32."},
+		{UnknownKey, "Near line 7, key 'extra': Key 'extra' doesn't match " +
+			"any field of 'toml.warningConfig'."},
+		{UnknownKey, "Near line 3, key 'typo': Key 'typo' doesn't match " +
+			"any field of 'toml.warningConfig'."},
+	}
+	warnings := md.Warnings()
+	if len(warnings) != len(want) {
+		t.Fatalf("Expected %d warnings but got %v", len(want), warnings)
+	}
+	for i, w := range warnings {
+		if w.Kind != want[i].kind || w.String() != want[i].msg {
+			t.Errorf("Expected %s warning %q but got %s warning %q",
+				want[i].kind, want[i].msg, w.Kind, w.String())
+		}
+	}
+}
+
+func TestWarningsNone(t *testing.T) {
+	var v map[string]interface{}
+	md, err := Decode(`name = "web"`+"\nratio = 0.5", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(md.Warnings()) > 0 {
+		t.Errorf("Expected no warnings but got %v", md.Warnings())
+	}
+}
+
+func TestWarningsBigNumbers(t *testing.T) {
+	var v struct {
+		Pi float64 `toml:"pi"`
+	}
+	md, err := decodeBig("pi = 3.14159265358979323846264", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := md.Warnings()
+	if len(warnings) != 1 || warnings[0].Kind != LossyConversion ||
+		!strings.Contains(warnings[0].Message, "to fit float64") {
+
+		t.Errorf("Expected a lossy conversion into float64 but got %v",
+			warnings)
+	}
+}
+
+func TestPromoteWarnings(t *testing.T) {
+	var v warningConfig
+	dec := NewDecoder(strings.NewReader(warningData))
+	dec.PromoteWarnings = []WarningKind{UnknownKey}
+	md, err := dec.Decode(&v)
+	se, ok := err.(*StrictError)
+	if !ok || len(se.Violations) != 2 {
+		t.Fatalf("Expected 2 violations but got %v", err)
+	}
+	want := "Near line 3, key 'typo': " +
+		"Key 'typo' doesn't match any field of 'toml.warningConfig'."
+	if se.Violations[0].String() != want {
+		t.Errorf("Expected %q but got %q", want, se.Violations[0])
+	}
+	if v.Name != "web" || len(md.Warnings()) != 5 {
+		t.Errorf("Expected the data to be decoded with its warnings")
+	}
+
+	var p struct {
+		Extra Primitive `toml:"extra"`
+	}
+	dec = NewDecoder(strings.NewReader(warningData))
+	dec.PromoteWarnings = []WarningKind{LossyConversion}
+	md, err = dec.Decode(&map[string]interface{}{})
+	if err == nil || !strings.Contains(err.Error(), "key 'pi'") {
+		t.Errorf("Expected a lossy conversion error but got %v", err)
+	}
+
+	dec = NewDecoder(strings.NewReader("[extra]\nx = 1\ny = 2"))
+	dec.PromoteWarnings = []WarningKind{UnknownKey}
+	if md, err = dec.Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	var extra struct {
+		X int `toml:"x"`
+	}
+	err = md.PrimitiveDecode(p.Extra, &extra)
+	want = "Near line 3, key 'extra.y': Key 'y' doesn't match any field"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Expected %q but got %v", want, err)
+	}
+}
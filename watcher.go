@@ -0,0 +1,326 @@
+package toml
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Watcher polls TOML files for changes and decodes them again when they
+// change, so that long running programs can pick up new configuration
+// without restarting. Every decode is strict, as with DecodeStrict, and goes
+// into a fresh value. When the files no longer decode, the last good value
+// is kept and subscribers are told about the error.
+//
+// Several files are merged by a Loader in the order given, so later files
+// take precedence. Files pulled in through the Decoder's IncludeKey are
+// watched too; the set of them is worked out again on every decode, so files
+// that a glob matches only later are noticed once another watched file
+// changes.
+type Watcher struct {
+	// Interval is the time between polls. It defaults to one second.
+	Interval time.Duration
+
+	// Decoder, when set, supplies the options used to decode the files.
+	Decoder *Decoder
+
+	// IgnoreFields holds patterns of keys that may appear in the files
+	// without a corresponding Go value. See DecodeStrict.
+	IgnoreFields map[string]interface{}
+
+	files    []string
+	newValue func() interface{}
+
+	// polling serializes polls, and mu guards the fields below it. The keys
+	// of stats are the files being watched.
+	polling     sync.Mutex
+	mu          sync.Mutex
+	stats       map[string]fileStat
+	value       interface{}
+	md          MetaData
+	subscribers []func(Update)
+	stop        chan struct{}
+}
+
+// Update describes the outcome of decoding the files of a Watcher again
+// after they changed.
+type Update struct {
+	// Value is the newly decoded value, as returned by the function given
+	// to NewWatcher. If Err is set, it is the last good value instead.
+	Value interface{}
+
+	// MetaData describes Value.
+	MetaData MetaData
+
+	// Changed lists the keys whose values were added, removed or changed,
+	// in sorted order. It is empty if Err is set.
+	Changed []Key
+
+	// Err is the error that prevented the files from being decoded.
+	Err error
+}
+
+// fileStat is what a Watcher knows about a file to tell if it changed.
+type fileStat struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// NewWatcher returns a Watcher for the TOML files `files`. The function
+// `newValue` returns a pointer to a fresh value to decode the files into each
+// time they change, such as
+//
+//	func() interface{} { return new(Config) }
+func NewWatcher(newValue func() interface{}, files ...string) *Watcher {
+	return &Watcher{files: files, newValue: newValue}
+}
+
+// Subscribe adds a function that is called with every Update. Subscribers
+// are called one after another from the polling goroutine, so they should
+// return quickly. They may call the methods of the Watcher, including Stop.
+func (w *Watcher) Subscribe(fn func(Update)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Start decodes the files and starts polling them for changes. It returns
+// an error, and doesn't start polling, if the files can't be decoded.
+func (w *Watcher) Start() error {
+	stats := statAll(w.watched(nil))
+	v, md, files, err := w.load()
+	if err != nil {
+		return err
+	}
+	stats = w.restat(stats, files)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		return e("Watcher has already been started.")
+	}
+	w.stats, w.value, w.md = stats, v, md
+	w.stop = make(chan struct{})
+	go w.run(w.stop)
+	return nil
+}
+
+// Stop stops polling. It waits for a poll in progress to decode the files,
+// but not for its subscribers to return, so that they may call Stop.
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	stop := w.stop
+	w.stop = nil
+	w.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		w.polling.Lock()
+		w.polling.Unlock()
+	}
+}
+
+// Current returns the last value decoded successfully and its MetaData.
+func (w *Watcher) Current() (interface{}, MetaData) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.value, w.md
+}
+
+func (w *Watcher) run(stop chan struct{}) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.poll(stop)
+		}
+	}
+}
+
+// Poll checks the files for changes once, decoding them again and notifying
+// the subscribers if they changed. It returns true if they did. Poll is
+// called periodically once the Watcher is started, but may also be called
+// directly.
+func (w *Watcher) Poll() bool {
+	return w.poll(nil)
+}
+
+// poll is Poll, except that it does nothing once `stop` is closed. The
+// subscribers are notified after `polling` is released.
+func (w *Watcher) poll(stop chan struct{}) bool {
+	update, subscribers, ok := w.reload(stop)
+	if !ok {
+		return false
+	}
+	for _, fn := range subscribers {
+		fn(update)
+	}
+	return true
+}
+
+// reload decodes the files again if they changed, returning the update and
+// the subscribers to notify of it, or false if they didn't change.
+func (w *Watcher) reload(
+	stop chan struct{}) (Update, []func(Update), bool) {
+
+	w.polling.Lock()
+	defer w.polling.Unlock()
+
+	select {
+	case <-stop:
+		return Update{}, nil, false
+	default:
+	}
+
+	w.mu.Lock()
+	watched := make([]string, 0, len(w.stats))
+	for fpath := range w.stats {
+		watched = append(watched, fpath)
+	}
+	w.mu.Unlock()
+
+	stats := statAll(watched)
+
+	w.mu.Lock()
+	if reflect.DeepEqual(stats, w.stats) {
+		w.mu.Unlock()
+		return Update{}, nil, false
+	}
+	w.stats = stats
+	w.mu.Unlock()
+
+	v, md, files, err := w.load()
+	if err == nil {
+		stats = w.restat(stats, files)
+	}
+
+	w.mu.Lock()
+	update := Update{Value: w.value, MetaData: w.md, Err: err}
+	if err == nil {
+		update = Update{
+			Value:    v,
+			MetaData: md,
+			Changed:  changedKeys(w.md.mapping, md.mapping, nil),
+		}
+		w.value, w.md, w.stats = v, md, stats
+	}
+	subscribers := w.subscribers
+	w.mu.Unlock()
+	return update, subscribers, true
+}
+
+// load decodes the files into a fresh value and checks them strictly. It
+// also returns the absolute paths of every file read, includes and all.
+func (w *Watcher) load() (interface{}, MetaData, []string, error) {
+	l := Loader{Decoder: w.Decoder}
+	for _, fpath := range w.files {
+		l.Add(FileLayer(fpath))
+	}
+
+	v := w.newValue()
+	if !isPointer(v) {
+		return nil, MetaData{}, nil, e("Must use pointer type for strict "+
+			"decoding: [%s]", v)
+	}
+	merged, err := l.merge(reflect.TypeOf(v))
+	if err != nil {
+		return nil, MetaData{}, nil, err
+	}
+
+	c := newChecker(l.decoder(), w.IgnoreFields, merged.positions)
+	c.checkTypeStructAsType(merged.mapping, reflect.TypeOf(v), nil)
+	if err := c.err(); err != nil {
+		return nil, MetaData{}, nil, err
+	}
+	md, err := l.decoder().unifyParsed(merged, v)
+	if err != nil {
+		return nil, MetaData{}, nil, err
+	}
+	return v, md, merged.files, nil
+}
+
+// watched returns the files to watch: those given to NewWatcher and the
+// files `files` that decoding them read, each once.
+func (w *Watcher) watched(files []string) []string {
+	seen := make(map[string]bool, len(w.files)+len(files))
+	watched := make([]string, 0, len(w.files)+len(files))
+	for _, fpath := range append(append([]string{}, w.files...), files...) {
+		abs := absPath(fpath)
+		if !seen[abs] {
+			seen[abs] = true
+			watched = append(watched, abs)
+		}
+	}
+	return watched
+}
+
+// restat returns the stats of the files to watch after decoding read the
+// files `files`. Files already in `stats` keep the stats they had, which
+// were taken before decoding, so that changes made while decoding aren't
+// missed.
+func (w *Watcher) restat(
+	stats map[string]fileStat, files []string) map[string]fileStat {
+
+	watched := w.watched(files)
+	restated := make(map[string]fileStat, len(watched))
+	for _, fpath := range watched {
+		if st, ok := stats[fpath]; ok {
+			restated[fpath] = st
+		} else {
+			restated[fpath] = statFile(fpath)
+		}
+	}
+	return restated
+}
+
+func statAll(files []string) map[string]fileStat {
+	stats := make(map[string]fileStat, len(files))
+	for _, fpath := range files {
+		stats[fpath] = statFile(fpath)
+	}
+	return stats
+}
+
+func statFile(fpath string) fileStat {
+	info, err := os.Stat(fpath)
+	if err != nil {
+		return fileStat{}
+	}
+	return fileStat{true, info.Size(), info.ModTime()}
+}
+
+// changedKeys returns the keys, found in the tables `before` and `after` at
+// `context`, whose values were added, removed or changed.
+func changedKeys(before, after map[string]interface{}, context Key) []Key {
+	union := make(map[string]interface{}, len(after))
+	for k := range before {
+		union[k] = true
+	}
+	for k := range after {
+		union[k] = true
+	}
+
+	changed := make([]Key, 0)
+	for _, k := range sortedKeys(union) {
+		ov, oexists := before[k]
+		nv, nexists := after[k]
+		ot, oIsTable := ov.(map[string]interface{})
+		nt, nIsTable := nv.(map[string]interface{})
+		switch {
+		case oIsTable && nIsTable:
+			changed = append(changed, changedKeys(ot, nt, context.add(k))...)
+		case oexists != nexists || !reflect.DeepEqual(ov, nv):
+			changed = append(changed, context.add(k))
+		}
+	}
+	return changed
+}
@@ -0,0 +1,200 @@
+package toml
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type watcherConfig struct {
+	Title    string
+	Database struct {
+		Server string
+		Ports  []int
+	}
+}
+
+// rewrite replaces the contents of a watched file, making sure that its
+// modification time changes.
+func rewrite(t *testing.T, fpath, data string) {
+	if err := ioutil.WriteFile(fpath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(fpath, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.toml": "title = \"base\"\n[database]\nserver = \"a\"\n" +
+			"ports = [1]\n",
+		"host.toml": "[database]\nserver = \"b\"\n",
+	})
+	base := filepath.Join(dir, "base.toml")
+	host := filepath.Join(dir, "host.toml")
+
+	w := NewWatcher(func() interface{} { return new(watcherConfig) },
+		base, host)
+	var updates []Update
+	w.Subscribe(func(u Update) {
+		updates = append(updates, u)
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// Poll by hand from here on.
+	w.Stop()
+
+	v, md := w.Current()
+	if conf := v.(*watcherConfig); conf.Database.Server != "b" {
+		t.Fatalf("Expected the host file to take precedence, got %+v", conf)
+	}
+	if md.Source("database", "server") != host+":2" {
+		t.Fatalf("Unexpected source %q", md.Source("database", "server"))
+	}
+	if w.Poll() || len(updates) != 0 {
+		t.Fatal("Expected no update for unchanged files")
+	}
+
+	rewrite(t, base, "title = \"base\"\n[database]\nserver = \"a\"\n"+
+		"ports = [1, 2]\nextra = true\n")
+	if !w.Poll() || len(updates) != 1 {
+		t.Fatal("Expected an update for a changed file")
+	}
+	if !strings.Contains(updates[0].Err.Error(),
+		"key 'database.extra': Configuration contains key [extra]") {
+
+		t.Fatalf("Expected a strict error, got %v", updates[0].Err)
+	}
+	if updates[0].Value != v {
+		t.Fatal("Expected the last good value to be kept")
+	}
+
+	rewrite(t, base, "title = \"new\"\n[database]\nserver = \"a\"\n"+
+		"ports = [1, 2]\n")
+	if !w.Poll() || len(updates) != 2 || updates[1].Err != nil {
+		t.Fatalf("Expected a good update, got %+v", updates)
+	}
+	conf := updates[1].Value.(*watcherConfig)
+	if conf.Title != "new" || len(conf.Database.Ports) != 2 {
+		t.Fatalf("Unexpected config: %+v", conf)
+	}
+	if v.(*watcherConfig).Title != "base" {
+		t.Fatal("Expected a fresh value to be decoded into")
+	}
+	want := []Key{{"database", "ports"}, {"title"}}
+	if !reflect.DeepEqual(updates[1].Changed, want) {
+		t.Fatalf("Expected changed keys %v but got %v", want,
+			updates[1].Changed)
+	}
+	if cur, _ := w.Current(); cur != updates[1].Value {
+		t.Fatal("Expected the new value to be current")
+	}
+}
+
+func TestWatcherIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.toml":     "include = \"conf.d/*.toml\"\ntitle = \"main\"\n",
+		"conf.d/a.toml": "[database]\nserver = \"a\"\n",
+	})
+	main := filepath.Join(dir, "main.toml")
+	included := filepath.Join(dir, "conf.d", "a.toml")
+
+	w := NewWatcher(func() interface{} { return new(watcherConfig) }, main)
+	w.Decoder = NewDecoder(nil)
+	w.Decoder.IncludeKey = "include"
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	w.Stop()
+
+	rewrite(t, included, "[database]\nserver = \"b\"\n")
+	if !w.Poll() {
+		t.Fatal("Expected a change to the included file to be picked up")
+	}
+	if v, _ := w.Current(); v.(*watcherConfig).Database.Server != "b" {
+		t.Fatalf("Unexpected value after the change: %+v", v)
+	}
+
+	// Once nothing includes the file, it is no longer watched.
+	rewrite(t, main, "title = \"main\"\n")
+	if !w.Poll() {
+		t.Fatal("Expected the change to the main file to be picked up")
+	}
+	rewrite(t, included, "[database]\nserver = \"c\"\n")
+	if w.Poll() {
+		t.Fatal("Expected a file no longer included to be ignored")
+	}
+}
+
+func TestWatcherPolls(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"conf.toml": "title = \"a\"\n"})
+	fpath := filepath.Join(dir, "conf.toml")
+
+	w := NewWatcher(func() interface{} { return new(watcherConfig) }, fpath)
+	w.Interval = 10 * time.Millisecond
+	updates := make(chan Update, 1)
+	w.Subscribe(func(u Update) {
+		updates <- u
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	rewrite(t, fpath, "title = \"b\"\n")
+	select {
+	case u := <-updates:
+		if u.Err != nil || u.Value.(*watcherConfig).Title != "b" {
+			t.Fatalf("Unexpected update: %+v", u)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the change to be picked up")
+	}
+}
+
+func TestWatcherStopFromSubscriber(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"conf.toml": "title = \"a\"\n"})
+	fpath := filepath.Join(dir, "conf.toml")
+
+	w := NewWatcher(func() interface{} { return new(watcherConfig) }, fpath)
+	w.Interval = 10 * time.Millisecond
+	stopped := make(chan struct{})
+	w.Subscribe(func(u Update) {
+		if u.Err != nil {
+			w.Stop()
+			close(stopped)
+		}
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	rewrite(t, fpath, "title = 1\n")
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a subscriber to be able to stop the watcher")
+	}
+
+	// Nothing is polled once the watcher has stopped.
+	rewrite(t, fpath, "title = \"b\"\n")
+	time.Sleep(50 * time.Millisecond)
+	if v, _ := w.Current(); v.(*watcherConfig).Title != "a" {
+		t.Fatalf("Expected no polls after stopping, got %+v", v)
+	}
+}
+
+func TestWatcherStartError(t *testing.T) {
+	w := NewWatcher(func() interface{} { return new(watcherConfig) },
+		filepath.Join(t.TempDir(), "missing.toml"))
+	if err := w.Start(); err == nil {
+		t.Fatal("Expected an error for a missing file")
+	}
+}